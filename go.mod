@@ -3,12 +3,12 @@ module github.com/995933447/log-go
 go 1.24.0
 
 require (
-	github.com/995933447/gofiler v0.0.0-20250611152943-58698ea59433
 	github.com/995933447/runtimeutil v0.0.0-20250616093445-cac0b5d5db5e
 	github.com/995933447/simpletrace v0.0.0-20230217061256-c25a914bd376
 	github.com/995933447/std-go v0.0.0-20220806175833-ab3496c0b696
 	github.com/BurntSushi/toml v1.5.0
 	github.com/json-iterator/go v1.1.12
+	github.com/klauspost/compress v1.17.11
 )
 
 require (
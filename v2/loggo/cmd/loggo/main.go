@@ -0,0 +1,37 @@
+// loggo 查询FileWriter写出的日志目录
+//
+//	loggo query [flags] dir...
+//	loggo trace [flags] traceId [node=]dir...
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage:
+  loggo query [flags] dir...                 按级别、时间、模块、trace、gid、调用方过滤日志
+  loggo trace [flags] traceId [node=]dir...  合并多个节点中同一trace的日志,按时间排列
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "query":
+		err = runQuery(os.Args[2:])
+	case "trace":
+		err = runTrace(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
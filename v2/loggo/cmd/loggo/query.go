@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
+	"github.com/995933447/log-go/v2/loggo/logger/query"
+)
+
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputCount = "count"
+)
+
+// timeLayouts -since/-until支持的时间格式,也支持相对当前的时长,例如1h表示一小时前
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// jsonRecord -o json输出的一行
+type jsonRecord struct {
+	Time   string `json:"time"`
+	Module string `json:"module"`
+	Trace  string `json:"trace"`
+	Gid    int64  `json:"gid"`
+	Level  string `json:"level"`
+	Func   string `json:"func"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Msg    string `json:"msg"`
+	Stack  string `json:"stack,omitempty"`
+	Source string `json:"source"`
+}
+
+func runQuery(args []string) error {
+	return queryCmd(args, os.Stdout, os.Stderr)
+}
+
+func queryCmd(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		prefix      = flags.String("prefix", "", "只读取以该前缀开头的日志文件")
+		level       = flags.String("level", "", "最低级别: DBG/INFO/IMP/WARN/ERR/PANIC/FATAL")
+		since       = flags.String("since", "", "开始时间(包含),例如 2025-01-02 15:04:05 或 1h")
+		until       = flags.String("until", "", "结束时间(不包含),格式同-since")
+		module      = flags.String("module", "", "模块名")
+		trace       = flags.String("trace", "", "trace id")
+		gid         = flags.Int64("gid", 0, "协程id")
+		caller      = flags.String("caller", "", "函数:文件:行号 中包含的子串")
+		callerRegex = flags.String("caller-regex", "", "函数:文件:行号 匹配的正则")
+		output      = flags.String("o", outputText, "输出格式: text/json/count")
+		countBy     = flags.String("by", "level", "-o count时的统计字段: level/module/trace/gid/caller/func/file/minute/hour")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("no log dir given")
+	}
+
+	filter, err := newFilter(*level, *since, *until, *callerRegex, time.Now())
+	if err != nil {
+		return err
+	}
+	filter.Module = *module
+	filter.Trace = *trace
+	filter.Gid = *gid
+	filter.Caller = *caller
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+
+	var emit func(file string, rec *fmts.Record) error
+	counts := make(map[string]int)
+	switch *output {
+	case outputText:
+		emit = func(file string, rec *fmts.Record) error {
+			_, err := out.WriteString(rec.Raw)
+			return err
+		}
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		emit = func(file string, rec *fmts.Record) error {
+			return enc.Encode(toJSONRecord(file, rec))
+		}
+	case outputCount:
+		keyFunc, ok := countKeyFuncs[*countBy]
+		if !ok {
+			return fmt.Errorf("unknown count field %s", *countBy)
+		}
+		emit = func(file string, rec *fmts.Record) error {
+			counts[keyFunc(rec)]++
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown output format %s", *output)
+	}
+
+	for _, dir := range flags.Args() {
+		if err = query.Scan(dir, *prefix, filter, emit); err != nil {
+			return err
+		}
+	}
+
+	if *output == outputCount {
+		writeCounts(out, counts)
+	}
+
+	return nil
+}
+
+func newFilter(level, since, until, callerRegex string, now time.Time) (*query.Filter, error) {
+	filter := &query.Filter{}
+	if level != "" {
+		minLevel, ok := logger.StrToLevelMap[strings.ToUpper(level)]
+		if !ok {
+			return nil, fmt.Errorf("unknown level %s", level)
+		}
+		filter.MinLevel = minLevel
+	}
+
+	var err error
+	if filter.Since, err = parseTime(since, now); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseTime(until, now); err != nil {
+		return nil, err
+	}
+
+	if callerRegex != "" {
+		if filter.CallerRegexp, err = regexp.Compile(callerRegex); err != nil {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
+
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s", s)
+}
+
+func toJSONRecord(file string, rec *fmts.Record) *jsonRecord {
+	return &jsonRecord{
+		Time:   rec.Time.Format(time.RFC3339Nano),
+		Module: rec.Module,
+		Trace:  rec.Trace,
+		Gid:    rec.Gid,
+		Level:  logger.LevelToStrMap[rec.Level],
+		Func:   rec.FuncName,
+		File:   rec.FileName,
+		Line:   rec.Line,
+		Msg:    rec.Msg,
+		Stack:  rec.Stack,
+		Source: file,
+	}
+}
+
+var countKeyFuncs = map[string]func(rec *fmts.Record) string{
+	"level":  func(rec *fmts.Record) string { return logger.LevelToStrMap[rec.Level] },
+	"module": func(rec *fmts.Record) string { return rec.Module },
+	"trace":  func(rec *fmts.Record) string { return rec.Trace },
+	"gid":    func(rec *fmts.Record) string { return strconv.FormatInt(rec.Gid, 10) },
+	"caller": query.FormatCaller,
+	"func":   func(rec *fmts.Record) string { return rec.FuncName },
+	"file":   func(rec *fmts.Record) string { return rec.FileName },
+	"minute": func(rec *fmts.Record) string { return rec.Time.Format("2006-01-02 15:04") },
+	"hour":   func(rec *fmts.Record) string { return rec.Time.Format("2006-01-02 15") },
+}
+
+// writeCounts 按数量从多到少输出,数量相同按字段值排序
+func writeCounts(out io.Writer, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(out, "%d\t%s\n", counts[key], key)
+	}
+}
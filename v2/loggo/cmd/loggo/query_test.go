@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryCmd(t *testing.T) {
+	dir := t.TempDir()
+	content := "" +
+		"[01-02T11:00:00.0000] [order] [t1][1] INFO main.order:order.go:5 created\\tok\n" +
+		"[01-02T11:00:01.0000] [order] [t1][1] \x1b[91mERR main.pay:pay.go:30\x1b[0m failed\n" +
+		"\tmain.pay\n" +
+		"\t\t/src/pay.go:30\n" +
+		"[01-02T11:00:02.0000] [user] [t2][2] INFO main.login:login.go:10 login\n"
+	if err := os.WriteFile(dir+"/app.001.txt", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		var stdout, stderr bytes.Buffer
+		if err := queryCmd(append(args, dir), &stdout, &stderr); err != nil {
+			t.Fatalf("%v: %s", err, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	if out := run("-trace", "t1", "-level", "err"); !strings.HasPrefix(out, "[01-02T11:00:01.0000]") || !strings.HasSuffix(out, "\t\t/src/pay.go:30\n") {
+		t.Fatalf("text output should keep original lines, got %q", out)
+	}
+
+	var rec jsonRecord
+	if err := json.Unmarshal([]byte(run("-o", "json", "-caller", "order.go")), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Msg != "created\tok" || rec.Level != "INFO" || rec.Func != "main.order" || rec.Source != dir+"/app.001.txt" {
+		t.Fatalf("unexpected json record %+v", rec)
+	}
+
+	if out := run("-o", "count", "-by", "module"); out != "2\torder\n1\tuser\n" {
+		t.Fatalf("unexpected counts %q", out)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local)
+	if got, err := parseTime("1h", now); err != nil || !got.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("unexpected %s %v", got, err)
+	}
+	if got, err := parseTime("2025-01-02 15:00:00", now); err != nil || !got.Equal(time.Date(2025, 1, 2, 15, 0, 0, 0, time.Local)) {
+		t.Fatalf("unexpected %s %v", got, err)
+	}
+	if _, err := parseTime("yesterday", now); err == nil {
+		t.Fatal("expect error for invalid time")
+	}
+}
+
+func TestTraceCmd(t *testing.T) {
+	nodeA, nodeB := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(nodeA+"/app.2025010211_1_001.txt", []byte("[01-02T11:00:00.0000] [gateway] [t1][1] INFO main.recv:recv.go:1 recv\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nodeB+"/app.2025010211_2_001.txt", []byte("[01-02T11:00:00.2500] [order] [t1][3] INFO main.order:order.go:1 order\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := traceCmd([]string{"t1", nodeB, "gw=" + nodeA}, &stdout, &stderr); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	expect := "+0s        [gw gateway] [01-02T11:00:00.0000] [gateway] [t1][1] INFO main.recv:recv.go:1 recv\n" +
+		"+250ms     [2 order] [01-02T11:00:00.2500] [order] [t1][3] INFO main.order:order.go:1 order\n"
+	if stdout.String() != expect {
+		t.Fatalf("unexpected timeline %q", stdout.String())
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/995933447/log-go/v2/loggo/logger/query"
+)
+
+// jsonTimelineEntry -o json输出的一行,在日志之外带上节点和与上一行的时间差
+type jsonTimelineEntry struct {
+	*jsonRecord
+	Node    string  `json:"node"`
+	DeltaMs float64 `json:"delta_ms"`
+}
+
+func runTrace(args []string) error {
+	return traceCmd(args, os.Stdout, os.Stderr)
+}
+
+func traceCmd(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("trace", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		prefix = flags.String("prefix", "", "只读取以该前缀开头的日志文件")
+		output = flags.String("o", outputText, "输出格式: text/json")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return errors.New("usage: loggo trace [flags] traceId [node=]dir...")
+	}
+
+	var dirs []query.NodeDir
+	for _, arg := range flags.Args()[1:] {
+		dirs = append(dirs, parseNodeDir(arg))
+	}
+
+	entries, err := query.TraceTimeline(dirs, *prefix, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+
+	switch *output {
+	case outputText:
+		for _, entry := range entries {
+			if _, err = fmt.Fprintf(out, "%-10s [%s %s] %s", "+"+entry.Delta.String(), entry.Node, entry.Record.Module, entry.Record.Raw); err != nil {
+				return err
+			}
+		}
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		for _, entry := range entries {
+			err = enc.Encode(&jsonTimelineEntry{
+				jsonRecord: toJSONRecord(entry.File, entry.Record),
+				Node:       entry.Node,
+				DeltaMs:    float64(entry.Delta.Microseconds()) / 1000,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown output format %s", *output)
+	}
+
+	return nil
+}
+
+// parseNodeDir 参数为 节点名=目录 时使用指定的节点名
+func parseNodeDir(arg string) query.NodeDir {
+	if node, dir, ok := strings.Cut(arg, "="); ok && node != "" {
+		return query.NodeDir{Node: node, Dir: dir}
+	}
+	return query.NodeDir{Dir: arg}
+}
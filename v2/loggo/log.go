@@ -1,7 +1,10 @@
 package loggo
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
@@ -83,8 +86,8 @@ func InitFileLogger(baseDir, filePrefix string, skipCall int, cfgLoader *logger.
 		SkipCall:                 skipCall,
 		LogCfgLoader:             cfgLoader,
 		CheckFileFullIntervalSec: 10,
-		BufChanLen:               100000,
-		CheckTimeToOpenNewFile:   OpenNewFileByByDateHour,
+		BufSizeBytes:             8 * 1024 * 1024,
+		RotationPolicy:           NewNodeHourlyRotation(cfgLoader),
 		OnLogErr: func(err error) {
 			fmt.Println(err)
 		},
@@ -109,8 +112,8 @@ func InitWithAlertFileLogger(baseDir, filePrefix string, skipCall int, cfgLoader
 		SkipCall:                 skipCall,
 		LogCfgLoader:             cfgLoader,
 		CheckFileFullIntervalSec: 10,
-		BufChanLen:               100000,
-		CheckTimeToOpenNewFile:   OpenNewFileByByDateHour,
+		BufSizeBytes:             8 * 1024 * 1024,
+		RotationPolicy:           NewNodeHourlyRotation(cfgLoader),
 		OnLogErr: func(err error) {
 			fmt.Println(err)
 		},
@@ -127,6 +130,16 @@ func InitWithAlertFileLogger(baseDir, filePrefix string, skipCall int, cfgLoader
 	return withAlertLogger, nil
 }
 
+// NewNodeHourlyRotation 与writer.NewHourlyRotation一样按小时切换,另外分段大小取配置中的File.MaxFileSizeBytes,文件名带上节点id
+func NewNodeHourlyRotation(cfgLoader *logger.ConfLoader) writer.RotationPolicy {
+	return &writer.TimeSizeRotation{
+		Layout:    writer.HourlyRotationLayout,
+		CfgLoader: cfgLoader,
+		NameTag:   strconv.Itoa(nodeId),
+	}
+}
+
+// OpenNewFileByByDateHour Deprecated: 按分钟命名,同一分钟内按大小切换会写回同一个文件,使用NewNodeHourlyRotation
 func OpenNewFileByByDateHour(writer *writer.FileWriter, lastOpenFileTime *time.Time, isNeverOpenFile bool) (string, bool) {
 	fileName := writer.GetFilePrefix() + time.Now().Format("200601021504") + fmt.Sprintf("_%d", nodeId) + ".txt"
 
@@ -249,6 +262,40 @@ func fmtMsgForPrint(v ...interface{}) *MsgFormatForPrint {
 	return &MsgFormatForPrint{args: v}
 }
 
+// getDefaultLogger 写入时才取默认Logger,可以在InitDefaultLogger之前创建,重新初始化后也写到新的Logger
+func getDefaultLogger() *logger.Logger {
+	return defaultLogger
+}
+
+// NewStdLog 返回按指定级别写入默认Logger的标准库*log.Logger
+func NewStdLog(level logger.Level) *log.Logger {
+	return log.New(logger.NewLineWriterFunc(getDefaultLogger, level), "", 0)
+}
+
+// RedirectStdLog 把标准库log的全局输出按INFO级别写入默认Logger,返回恢复原输出的函数.
+// 默认Logger还没初始化时输出到标准错误
+func RedirectStdLog() func() {
+	flags, prefix, output := log.Flags(), log.Prefix(), log.Writer()
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(logger.NewLineWriterFunc(getDefaultLogger, logger.LevelInfo))
+	return func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(output)
+	}
+}
+
+// NewSlogHandler 写入默认Logger的slog.Handler,可配合slog.SetDefault使用
+func NewSlogHandler() slog.Handler {
+	return logger.NewSlogHandler(defaultLogger.GetWriter())
+}
+
+// With 返回绑定了fields的默认Logger的子Logger
+func With(fields ...logger.Field) *logger.Logger {
+	return defaultLogger.With(fields...)
+}
+
 func Debug(content interface{}) {
 	defaultLogger.Debug(content)
 }
@@ -305,6 +352,62 @@ func Fatalf(format string, args ...interface{}) {
 	defaultLogger.Fatalf(format, args...)
 }
 
+func DebugCtx(ctx context.Context, content interface{}) {
+	defaultLogger.DebugCtx(ctx, content)
+}
+
+func InfoCtx(ctx context.Context, content interface{}) {
+	defaultLogger.InfoCtx(ctx, content)
+}
+
+func ImportantCtx(ctx context.Context, content interface{}) {
+	defaultLogger.ImportantCtx(ctx, content)
+}
+
+func WarnCtx(ctx context.Context, content interface{}) {
+	defaultLogger.WarnCtx(ctx, content)
+}
+
+func ErrorCtx(ctx context.Context, content interface{}) {
+	defaultLogger.ErrorCtx(ctx, content)
+}
+
+func PanicCtx(ctx context.Context, content interface{}) {
+	defaultLogger.PanicCtx(ctx, content)
+}
+
+func FatalCtx(ctx context.Context, content interface{}) {
+	defaultLogger.FatalCtx(ctx, content)
+}
+
+func DebugfCtx(ctx context.Context, format string, args ...interface{}) {
+	defaultLogger.DebugfCtx(ctx, format, args...)
+}
+
+func InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	defaultLogger.InfofCtx(ctx, format, args...)
+}
+
+func ImportantfCtx(ctx context.Context, format string, args ...interface{}) {
+	defaultLogger.ImportantfCtx(ctx, format, args...)
+}
+
+func WarnfCtx(ctx context.Context, format string, args ...interface{}) {
+	defaultLogger.WarnfCtx(ctx, format, args...)
+}
+
+func ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	defaultLogger.ErrorfCtx(ctx, format, args...)
+}
+
+func PanicfCtx(ctx context.Context, format string, args ...interface{}) {
+	defaultLogger.PanicfCtx(ctx, format, args...)
+}
+
+func FatalfCtx(ctx context.Context, format string, args ...interface{}) {
+	defaultLogger.FatalfCtx(ctx, format, args...)
+}
+
 func PrintDebug(args ...interface{}) {
 	defaultLogger.Debug(fmtMsgForPrint(args...))
 }
@@ -339,6 +442,12 @@ func WriteBySkipCall(level logger.Level, skipCall int, format string, args ...in
 	}
 }
 
+func WriteBySkipCallCtx(ctx context.Context, level logger.Level, skipCall int, format string, args ...interface{}) {
+	if err := defaultLogger.WriteBySkipCallCtx(ctx, level, skipCall, append([]interface{}{format}, args...)...); err != nil {
+		fmt.Println(err)
+	}
+}
+
 func EnableStdoutPrinter() {
 	defaultLogger.EnableStdoutPrinter()
 }
@@ -0,0 +1,84 @@
+package loggo
+
+import (
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/log-go/v2/loggo/logger/writer"
+)
+
+// newTestFileLogger 写到临时目录的Logger,返回读取日志文件内容的函数
+func newTestFileLogger(t *testing.T) (*logger.Logger, func() string) {
+	cfgLoader, err := logger.NewConfLoader("", 10, &logger.LogConf{
+		File: logger.FileLogConf{Level: "INFO", LogInfoBeforeFileSizeBytes: -1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseDir := t.TempDir()
+	fileLogger, err := InitFileLogger(baseDir, "error", 5, cfgLoader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileWriter := fileLogger.GetWriter().(*writer.FileWriter)
+	t.Cleanup(func() {
+		_ = fileWriter.Close()
+	})
+
+	return fileLogger, func() string {
+		// 读取目录下所有日志文件,Loop可能还没打开文件
+		entries, err := os.ReadDir(baseDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var content []byte
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), writer.FileSuffix) {
+				continue
+			}
+			b, err := os.ReadFile(baseDir + "/" + entry.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			content = append(content, b...)
+		}
+		return string(content)
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	prevLogger := defaultLogger
+	defaultLogger = nil
+	restore := RedirectStdLog()
+	t.Cleanup(func() {
+		restore()
+		defaultLogger = prevLogger
+	})
+
+	// 默认Logger还没初始化
+	log.Printf("before init")
+
+	// 写入时才取默认Logger,重新初始化后写到新的Logger
+	firstLogger, readFirst := newTestFileLogger(t)
+	defaultLogger = firstLogger
+	log.Printf("to first")
+	secondLogger, readSecond := newTestFileLogger(t)
+	defaultLogger = secondLogger
+	log.Printf("to second")
+
+	for _, fileLogger := range []*logger.Logger{firstLogger, secondLogger} {
+		if err := fileLogger.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, second := readFirst(), readSecond()
+	if !strings.Contains(first, "TestRedirectStdLog:log_test.go") || !strings.Contains(first, "to first") || strings.Contains(first, "to second") {
+		t.Fatalf("unexpected first log %q", first)
+	}
+	if !strings.Contains(second, "to second") || strings.Contains(second, "to first") || strings.Contains(second, "before init") {
+		t.Fatalf("unexpected second log %q", second)
+	}
+}
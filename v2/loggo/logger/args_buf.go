@@ -0,0 +1,26 @@
+package logger
+
+import "sync"
+
+// argsBuf 写日志时复用的参数切片和Extra,writer同步使用完参数后放回池中
+type argsBuf struct {
+	args  []interface{}
+	extra Extra
+}
+
+var argsBufPool = sync.Pool{
+	New: func() interface{} {
+		return &argsBuf{args: make([]interface{}, 0, 8)}
+	},
+}
+
+func getArgsBuf() *argsBuf {
+	return argsBufPool.Get().(*argsBuf)
+}
+
+func (buf *argsBuf) free() {
+	clear(buf.args)
+	buf.args = buf.args[:0]
+	buf.extra = Extra{}
+	argsBufPool.Put(buf)
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,8 +11,9 @@ import (
 )
 
 type LogConf struct {
-	File       FileLogConf
-	AlertLevel string
+	File          FileLogConf
+	AlertLevel    string
+	LevelOverride LevelOverrideConf // 按调用方/logger覆盖File.Level
 }
 
 type FileLogConf struct {
@@ -22,22 +24,38 @@ type FileLogConf struct {
 	BillLogDir                  string // bill日志目录
 	StatLogDir                  string // stat日志目录
 	Level                       string // 日志最小级别
+	Format                      string // 日志格式text/json/logfmt,为空使用writer默认格式
 	DebugMsgMaxLen              int32  // debug日志消息最大长度,-1或者0代表不限制
 	InfoMsgMaxLen               int32  // info日志消息最大长度,-1或者0代表不限制
 	LogDebugBeforeFileSizeBytes int64  // 文件允许写入debug日志的大小阀值,-1代表不限制
 	LogInfoBeforeFileSizeBytes  int64  // 文件允许写入info日志的大小阀值,-1代表不限制
 	FileMaxRemainDays           int    // 文件最大保留天数
 	MaxRemainFileNum            int    // 保留文件数量
+	MaxTotalBytes               int64  // 同一前缀日志文件的总字节数上限,超出时从最早的文件开始删除,0代表不限制
 	CompressFrequentHours       int    // 压缩频率小时数
 	CompressAfterReachBytes     int64  // 压缩最小文件大小
+	CompressFormat              string // 压缩格式gzip/zstd,为空且设置了CompressFrequentHours时使用gzip,配置文件中为其他值时也使用gzip
+	SampleIntervalSec           int    // 按调用点采样的周期秒数,0或者SampleFirst和SampleThereafter都为0代表不采样
+	SampleFirst                 int    // 每个调用点每个周期内前N行全部输出
+	SampleThereafter            int    // 超过前N行后每M行输出一行,0代表全部丢弃
+	SampleMaxLevel              string // 参与采样的最高级别,默认INFO
+	StackTraceLevel             string // 达到该级别时输出调用堆栈,为空不输出
+	StackTraceMaxDepth          int32  // 堆栈最大帧数,默认32
 }
 
 func (f *FileLogConf) GetLevel() Level {
 	return TransStrToLevel(f.Level)
 }
 
+func (f *FileLogConf) GetSampleMaxLevel() Level {
+	return TransStrToLevel(f.SampleMaxLevel)
+}
+
 const defaultReloadCfgFileIntervalSec = 10
 
+// compressFormats writer支持的压缩格式
+var compressFormats = map[string]bool{"": true, "gzip": true, "zstd": true}
+
 func NewConfLoader(cfgFile string, reloadCfgFileIntervalSec uint32, defaultLogCfg *LogConf) (*ConfLoader, error) {
 	var loader ConfLoader
 	if reloadCfgFileIntervalSec <= 0 {
@@ -48,7 +66,7 @@ func NewConfLoader(cfgFile string, reloadCfgFileIntervalSec uint32, defaultLogCf
 	loader.cfgFile = cfgFile
 
 	loader.opLogCfgMu.Lock()
-	loader.cfg = defaultLogCfg
+	loader.setConf(defaultLogCfg)
 	loader.defaultLogCfg = defaultLogCfg
 	loader.opLogCfgMu.Unlock()
 
@@ -64,9 +82,11 @@ func NewConfLoader(cfgFile string, reloadCfgFileIntervalSec uint32, defaultLogCf
 type ConfLoader struct {
 	cfgFile                  string
 	cfg                      *LogConf
+	levelOverrides           *LevelOverrides
 	defaultLogCfg            *LogConf
 	opLogCfgMu               sync.RWMutex
 	reloadCfgFileIntervalSec uint32
+	badCompressFormat        string // 已经提示过的不支持的压缩格式,避免每次重新加载都提示
 }
 
 func (c *ConfLoader) GetConf() *LogConf {
@@ -75,6 +95,22 @@ func (c *ConfLoader) GetConf() *LogConf {
 	return c.cfg
 }
 
+// GetLevelOverrides 当前配置编译后的级别覆盖表,没有配置时返回nil
+func (c *ConfLoader) GetLevelOverrides() *LevelOverrides {
+	c.opLogCfgMu.RLock()
+	defer c.opLogCfgMu.RUnlock()
+	return c.levelOverrides
+}
+
+// setConf 调用方需持有写锁
+func (c *ConfLoader) setConf(cfg *LogConf) {
+	if c.cfg == cfg {
+		return
+	}
+	c.cfg = cfg
+	c.levelOverrides = NewLevelOverrides(&cfg.LevelOverride)
+}
+
 func (c *ConfLoader) init() {
 	go func() {
 		for {
@@ -90,7 +126,7 @@ func (c *ConfLoader) loadFile() error {
 	if c.cfgFile == "" {
 		c.opLogCfgMu.Lock()
 		defer c.opLogCfgMu.Unlock()
-		c.cfg = c.defaultLogCfg
+		c.setConf(c.defaultLogCfg)
 		return nil
 	}
 
@@ -100,12 +136,13 @@ func (c *ConfLoader) loadFile() error {
 		}
 		c.opLogCfgMu.Lock()
 		defer c.opLogCfgMu.Unlock()
-		c.cfg = c.defaultLogCfg
+		c.setConf(c.defaultLogCfg)
 		return nil
 	}
 
 	var cfg LogConf
-	if _, err := toml.DecodeFile(c.cfgFile, &cfg); err != nil {
+	md, err := toml.DecodeFile(c.cfgFile, &cfg)
+	if err != nil {
 		return err
 	}
 	c.opLogCfgMu.Lock()
@@ -122,11 +159,29 @@ func (c *ConfLoader) loadFile() error {
 	if cfg.File.StatLogDir == "" {
 		cfg.File.StatLogDir = c.defaultLogCfg.File.StatLogDir
 	}
-	c.cfg = &cfg
+	// 文件中有LevelOverride时以文件为准,热更新时写一个空的[LevelOverride]可以清除覆盖
+	if !md.IsDefined("LevelOverride") {
+		cfg.LevelOverride = c.defaultLogCfg.LevelOverride
+	}
+	c.checkCompressFormat(&cfg.File)
+	c.setConf(&cfg)
 
 	return nil
 }
 
+// checkCompressFormat 配置文件中的压缩格式不支持时使用gzip,同一个格式只提示一次.调用方需持有写锁
+func (c *ConfLoader) checkCompressFormat(cfg *FileLogConf) {
+	if compressFormats[strings.ToLower(cfg.CompressFormat)] {
+		c.badCompressFormat = ""
+		return
+	}
+	if c.badCompressFormat != cfg.CompressFormat {
+		fmt.Printf("unknown compress format %s in %s, use gzip instead\n", cfg.CompressFormat, c.cfgFile)
+		c.badCompressFormat = cfg.CompressFormat
+	}
+	cfg.CompressFormat = "gzip"
+}
+
 func (c *ConfLoader) SetDefaultLogConf(cfg *LogConf) {
 	if cfg == nil {
 		return
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadUnknownCompressFormat(t *testing.T) {
+	cfgFile := t.TempDir() + "/log.toml"
+	if err := os.WriteFile(cfgFile, []byte("[File]\nCompressFormat = \"zip\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loader, err := NewConfLoader(cfgFile, 3600, &LogConf{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format := loader.GetConf().File.CompressFormat; format != "gzip" {
+		t.Fatalf("unknown compress format should fall back to gzip, got %s", format)
+	}
+	if loader.badCompressFormat != "zip" {
+		t.Fatalf("unexpected bad compress format: %s", loader.badCompressFormat)
+	}
+
+	if err = os.WriteFile(cfgFile, []byte("[File]\nCompressFormat = \"ZSTD\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = loader.loadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if format := loader.GetConf().File.CompressFormat; format != "ZSTD" || loader.badCompressFormat != "" {
+		t.Fatalf("supported compress format should be kept, got %s", format)
+	}
+}
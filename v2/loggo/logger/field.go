@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+type FieldType int
+
+const (
+	FieldTypeAny FieldType = iota
+	FieldTypeString
+	FieldTypeInt
+	FieldTypeUint
+	FieldTypeFloat
+	FieldTypeBool
+	FieldTypeDuration
+	FieldTypeTime
+	FieldTypeError
+)
+
+// Field 结构化日志字段,通过String/Int/Err等构造
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	Str       string
+	Interface interface{}
+}
+
+func String(key, val string) Field {
+	return Field{Key: key, Type: FieldTypeString, Str: val}
+}
+
+func Int(key string, val int) Field {
+	return Field{Key: key, Type: FieldTypeInt, Integer: int64(val)}
+}
+
+func Int32(key string, val int32) Field {
+	return Field{Key: key, Type: FieldTypeInt, Integer: int64(val)}
+}
+
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Type: FieldTypeInt, Integer: val}
+}
+
+func Uint(key string, val uint) Field {
+	return Field{Key: key, Type: FieldTypeUint, Integer: int64(val)}
+}
+
+func Uint32(key string, val uint32) Field {
+	return Field{Key: key, Type: FieldTypeUint, Integer: int64(val)}
+}
+
+func Uint64(key string, val uint64) Field {
+	return Field{Key: key, Type: FieldTypeUint, Integer: int64(val)}
+}
+
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Type: FieldTypeFloat, Integer: int64(math.Float64bits(val))}
+}
+
+func Bool(key string, val bool) Field {
+	var i int64
+	if val {
+		i = 1
+	}
+	return Field{Key: key, Type: FieldTypeBool, Integer: i}
+}
+
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Type: FieldTypeDuration, Integer: int64(val)}
+}
+
+func Time(key string, val time.Time) Field {
+	return Field{Key: key, Type: FieldTypeTime, Interface: val}
+}
+
+func Err(err error) Field {
+	return NamedErr("err", err)
+}
+
+func NamedErr(key string, err error) Field {
+	if err == nil {
+		return Field{Key: key, Type: FieldTypeString, Str: "<nil>"}
+	}
+	return Field{Key: key, Type: FieldTypeError, Interface: err}
+}
+
+func Any(key string, val interface{}) Field {
+	switch v := val.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int32:
+		return Int32(key, v)
+	case int64:
+		return Int64(key, v)
+	case uint:
+		return Uint(key, v)
+	case uint32:
+		return Uint32(key, v)
+	case uint64:
+		return Uint64(key, v)
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return NamedErr(key, v)
+	}
+	return Field{Key: key, Type: FieldTypeAny, Interface: val}
+}
+
+// Value 返回字段原始值
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case FieldTypeString:
+		return f.Str
+	case FieldTypeInt:
+		return f.Integer
+	case FieldTypeUint:
+		return uint64(f.Integer)
+	case FieldTypeFloat:
+		return math.Float64frombits(uint64(f.Integer))
+	case FieldTypeBool:
+		return f.Integer == 1
+	case FieldTypeDuration:
+		return time.Duration(f.Integer)
+	}
+	return f.Interface
+}
+
+// AppendValue 把字段值按文本形式追加到b
+func (f Field) AppendValue(b []byte) []byte {
+	switch f.Type {
+	case FieldTypeString:
+		return append(b, f.Str...)
+	case FieldTypeInt:
+		return strconv.AppendInt(b, f.Integer, 10)
+	case FieldTypeUint:
+		return strconv.AppendUint(b, uint64(f.Integer), 10)
+	case FieldTypeFloat:
+		return strconv.AppendFloat(b, math.Float64frombits(uint64(f.Integer)), 'f', -1, 64)
+	case FieldTypeBool:
+		return strconv.AppendBool(b, f.Integer == 1)
+	case FieldTypeDuration:
+		return append(b, time.Duration(f.Integer).String()...)
+	case FieldTypeTime:
+		return f.Interface.(time.Time).AppendFormat(b, time.RFC3339Nano)
+	case FieldTypeError:
+		return append(b, f.Interface.(error).Error()...)
+	}
+	switch v := f.Interface.(type) {
+	case nil:
+		return append(b, "<nil>"...)
+	case fmt.Stringer:
+		return append(b, v.String()...)
+	}
+	j, err := jsoniter.ConfigFastest.Marshal(f.Interface)
+	if err != nil {
+		return append(b, fmt.Sprint(f.Interface)...)
+	}
+	return append(b, j...)
+}
+
+// Extra 随日志参数传递给Writer的附加信息,只能作为最后一个参数
+type Extra struct {
+	Fields []Field
+	Ctx    context.Context
+	PC     uintptr // 调用方pc,不为0时不再按skipCall查找调用方
+}
+
+// SplitExtra 从日志参数中拆出附加信息
+func SplitExtra(args []interface{}) ([]interface{}, *Extra) {
+	argsNum := len(args)
+	if argsNum == 0 {
+		return args, nil
+	}
+	if extra, ok := args[argsNum-1].(*Extra); ok {
+		return args[:argsNum-1], extra
+	}
+	return args, nil
+}
+
+// GetExtraFields 返回日志参数携带的结构化字段
+func GetExtraFields(args []interface{}) []Field {
+	if _, extra := SplitExtra(args); extra != nil {
+		return extra.Fields
+	}
+	return nil
+}
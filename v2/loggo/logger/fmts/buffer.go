@@ -0,0 +1,36 @@
+package fmts
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// 超过该大小的缓冲不放回池中,避免偶发的大日志长期占用内存
+const maxPooledMsgBufBytes = 64 * 1024
+
+type msgBuf struct {
+	b []byte
+}
+
+var msgBufPool = sync.Pool{
+	New: func() interface{} {
+		return &msgBuf{b: make([]byte, 0, 256)}
+	},
+}
+
+func getMsgBuf() *msgBuf {
+	return msgBufPool.Get().(*msgBuf)
+}
+
+func putMsgBuf(buf *msgBuf) {
+	if cap(buf.b) > maxPooledMsgBufBytes {
+		return
+	}
+	buf.b = buf.b[:0]
+	msgBufPool.Put(buf)
+}
+
+// String 不拷贝直接引用缓冲内容,只能在放回池之前使用
+func (buf *msgBuf) String() string {
+	return unsafe.String(unsafe.SliceData(buf.b), len(buf.b))
+}
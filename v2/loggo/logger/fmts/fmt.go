@@ -1,7 +1,23 @@
 package fmts
 
+import "strings"
+
 type Format int
 
 const (
 	FormatText Format = iota
+	FormatJSON
+	FormatLogfmt
 )
+
+var StrToFormatMap = map[string]Format{
+	"text":   FormatText,
+	"json":   FormatJSON,
+	"logfmt": FormatLogfmt,
+}
+
+// TransStrToFormat 配置中的格式名转换成Format,为空或者不支持时ok为false
+func TransStrToFormat(formatStr string) (Format, bool) {
+	formatType, ok := StrToFormatMap[strings.ToLower(formatStr)]
+	return formatType, ok
+}
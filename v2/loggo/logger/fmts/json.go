@@ -0,0 +1,127 @@
+package fmts
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONLine 一行一个json对象
+func appendJSONLine(b []byte, l *record) []byte {
+	b = append(b, `{"time":"`...)
+	b = time.Now().AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, `","module":`...)
+	b = appendJSONString(b, l.moduleName)
+	b = append(b, `,"trace":`...)
+	b = appendJSONString(b, l.trace)
+	b = append(b, `,"gid":`...)
+	b = strconv.AppendInt(b, l.gid, 10)
+	b = append(b, `,"level":`...)
+	b = appendJSONString(b, l.levelStr)
+	b = append(b, `,"caller":"`...)
+	b = appendJSONStringContent(b, l.funcName)
+	b = append(b, ':')
+	b = appendJSONStringContent(b, l.fileName)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(l.line), 10)
+	b = append(b, `","msg":`...)
+	b = appendJSONString(b, l.msg)
+	for _, field := range l.fields {
+		b = append(b, ',')
+		b = appendJSONString(b, field.Key)
+		b = append(b, ':')
+		b = appendJSONFieldValue(b, field)
+	}
+	b = append(b, '}', '\n')
+	return b
+}
+
+func appendJSONFieldValue(b []byte, field logger.Field) []byte {
+	switch field.Type {
+	case logger.FieldTypeInt:
+		return strconv.AppendInt(b, field.Integer, 10)
+	case logger.FieldTypeUint:
+		return strconv.AppendUint(b, uint64(field.Integer), 10)
+	case logger.FieldTypeFloat:
+		val := math.Float64frombits(uint64(field.Integer))
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return appendJSONString(b, strconv.FormatFloat(val, 'f', -1, 64))
+		}
+		return strconv.AppendFloat(b, val, 'f', -1, 64)
+	case logger.FieldTypeBool:
+		return strconv.AppendBool(b, field.Integer == 1)
+	case logger.FieldTypeAny:
+		if _, ok := field.Interface.(fmt.Stringer); !ok && field.Interface != nil {
+			if j, err := jsoniter.ConfigFastest.Marshal(field.Interface); err == nil {
+				return append(b, j...)
+			}
+		}
+	}
+	b = append(b, '"')
+	start := len(b)
+	b = field.AppendValue(b)
+	val := string(b[start:])
+	b = appendJSONStringContent(b[:start], val)
+	return append(b, '"')
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	b = appendJSONStringContent(b, s)
+	return append(b, '"')
+}
+
+// appendJSONStringContent 转义后追加,不包含首尾引号,非法utf8替换成�
+func appendJSONStringContent(b []byte, s string) []byte {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// JSONP安全,和encoding/json保持一致
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(b, s[start:]...)
+}
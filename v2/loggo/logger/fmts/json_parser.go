@@ -0,0 +1,99 @@
+package fmts
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+// ErrNotJSONLine 不是TraceFormatter输出的json格式日志行
+var ErrNotJSONLine = errors.New("not a json log line")
+
+// ParseJSONLine 解析一行json格式日志,额外的字段按 key=value 追加在Msg之后,和文本格式一致
+func ParseJSONLine(line string) (*Record, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, ErrNotJSONLine
+	}
+
+	rec := &Record{Raw: line}
+	var required int
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return nil, ErrNotJSONLine
+		}
+		key, _ := keyTok.(string)
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return nil, ErrNotJSONLine
+		}
+		if !rec.setField(key, jsonValueToString(raw), &required) {
+			return nil, ErrNotJSONLine
+		}
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') || required != structuredRequiredKeys {
+		return nil, ErrNotJSONLine
+	}
+
+	return rec, nil
+}
+
+// jsonValueToString 字符串取内容,其他类型保留原始json
+func jsonValueToString(raw json.RawMessage) string {
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+// structuredRequiredKeys json和logfmt格式必须有time/level/caller
+const structuredRequiredKeys = 3
+
+// setField 设置json和logfmt格式的字段,固定字段之外的按 key=value 追加在Msg之后,值不合法时返回false
+func (r *Record) setField(key, val string, required *int) bool {
+	var err error
+	switch key {
+	case "time":
+		*required++
+		r.Time, err = time.Parse(time.RFC3339Nano, val)
+		return err == nil
+	case "level":
+		*required++
+		var ok bool
+		r.Level, ok = logger.StrToLevelMap[val]
+		return ok
+	case "caller":
+		*required++
+		var ok bool
+		r.FuncName, r.FileName, r.Line, ok = splitCaller(val)
+		return ok
+	case "module":
+		r.Module = val
+	case "trace":
+		r.Trace = val
+	case "gid":
+		r.Gid, err = strconv.ParseInt(val, 10, 64)
+		return err == nil
+	case "msg":
+		// msg总是在其他字段之前输出,这里不依赖顺序
+		r.Msg = val + r.Msg
+	case StackFieldKey:
+		r.Stack = val
+	default:
+		// 与文本格式一样,值为空或者包含空格等字符时加引号
+		if val == "" || strings.ContainsAny(val, " =\"\n\r\t") {
+			val = strconv.Quote(val)
+		}
+		r.Msg += " " + key + "=" + val
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package fmts
+
+import (
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// appendLogfmtLine 按key=value输出一行,值包含空格/=/引号/控制字符时加引号转义
+func appendLogfmtLine(b []byte, r *record) []byte {
+	b = append(b, "time="...)
+	b = time.Now().AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, " level="...)
+	b = appendLogfmtValue(b, r.levelStr)
+	b = append(b, " module="...)
+	b = appendLogfmtValue(b, r.moduleName)
+	b = append(b, " trace="...)
+	b = appendLogfmtValue(b, r.trace)
+	b = append(b, " gid="...)
+	b = strconv.AppendInt(b, r.gid, 10)
+	b = append(b, " caller="...)
+	b = appendLogfmtValue(b, r.funcName+":"+r.fileName+":"+strconv.Itoa(r.line))
+	b = append(b, " msg="...)
+	b = appendLogfmtValue(b, r.msg)
+	for _, field := range r.fields {
+		b = append(b, ' ')
+		b = appendLogfmtKey(b, field.Key)
+		b = append(b, '=')
+		start := len(b)
+		b = field.AppendValue(b)
+		val := string(b[start:])
+		b = appendLogfmtValue(b[:start], val)
+	}
+	b = append(b, '\n')
+	return b
+}
+
+// appendLogfmtKey key不允许出现空格/=/引号,替换成_
+func appendLogfmtKey(b []byte, key string) []byte {
+	if key == "" {
+		return append(b, '_')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+func appendLogfmtValue(b []byte, val string) []byte {
+	if !logfmtNeedsQuote(val) {
+		return append(b, val...)
+	}
+	return appendJSONString(b, val)
+}
+
+func logfmtNeedsQuote(val string) bool {
+	if val == "" {
+		return true
+	}
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return true
+		}
+	}
+	return !utf8.ValidString(val)
+}
@@ -0,0 +1,66 @@
+package fmts
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrNotLogfmtLine 不是TraceFormatter输出的logfmt格式日志行
+var ErrNotLogfmtLine = errors.New("not a logfmt log line")
+
+// ParseLogfmtLine 解析一行logfmt格式日志,额外的字段按 key=value 追加在Msg之后,和文本格式一致
+func ParseLogfmtLine(line string) (*Record, error) {
+	rec := &Record{Raw: line}
+	rest := strings.TrimSuffix(line, "\n")
+	var required int
+	for rest != "" {
+		key, val, ok := strings.Cut(rest, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \"") {
+			return nil, ErrNotLogfmtLine
+		}
+
+		if strings.HasPrefix(val, `"`) {
+			// 引号中的值按json字符串转义
+			end := quotedEnd(val)
+			if end < 0 {
+				return nil, ErrNotLogfmtLine
+			}
+			var s string
+			if err := json.Unmarshal([]byte(val[:end]), &s); err != nil {
+				return nil, ErrNotLogfmtLine
+			}
+			val, rest = s, val[end:]
+		} else if sep := strings.IndexByte(val, ' '); sep >= 0 {
+			val, rest = val[:sep], val[sep:]
+		} else {
+			rest = ""
+		}
+
+		if !rec.setField(key, val, &required) {
+			return nil, ErrNotLogfmtLine
+		}
+
+		if rest, ok = strings.CutPrefix(rest, " "); !ok && rest != "" {
+			return nil, ErrNotLogfmtLine
+		}
+	}
+	if required != structuredRequiredKeys {
+		return nil, ErrNotLogfmtLine
+	}
+
+	return rec, nil
+}
+
+// quotedEnd s以引号开头,返回结束引号之后的位置,没有结束引号时返回-1
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
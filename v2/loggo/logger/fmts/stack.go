@@ -0,0 +1,98 @@
+package fmts
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+const (
+	StackFieldKey          = "stack"
+	defaultStackTraceDepth = 32
+	loggoPkgPrefix         = "github.com/995933447/log-go/"
+)
+
+// captureStack 从调用方开始抓取堆栈,callerPC不为0时从该pc所在帧开始,开头属于loggo的帧会被去掉
+func captureStack(skipCall int, callerPC uintptr, maxDepth int) string {
+	if maxDepth <= 0 {
+		maxDepth = defaultStackTraceDepth
+	}
+
+	// 多抓一些用于跳过callerPC之前以及loggo内部的帧
+	rpc := make([]uintptr, maxDepth+32)
+	var n int
+	if callerPC == 0 {
+		n = runtime.Callers(skipCall+2, rpc)
+	} else {
+		n = runtime.Callers(2, rpc)
+		for i, pc := range rpc[:n] {
+			if pc == callerPC {
+				rpc = rpc[i:]
+				n -= i
+				break
+			}
+		}
+	}
+
+	var (
+		b        strings.Builder
+		depth    int
+		inLoggo  = true
+		frames   = runtime.CallersFrames(rpc[:n])
+		hasFrame = n > 0
+	)
+	for hasFrame && depth < maxDepth {
+		var frame runtime.Frame
+		frame, hasFrame = frames.Next()
+		if inLoggo && strings.HasPrefix(frame.Function, loggoPkgPrefix) && !strings.HasSuffix(frame.File, "_test.go") {
+			continue
+		}
+		inLoggo = false
+		if frame.Function == "runtime.goexit" {
+			break
+		}
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte('\n')
+		depth++
+	}
+
+	return b.String()
+}
+
+func (f *TraceFormatter) needStackTrace(level logger.Level) bool {
+	levelStr := f.cfgLoader.GetConf().File.StackTraceLevel
+	if levelStr == "" {
+		return false
+	}
+	stackTraceLevel, ok := logger.StrToLevelMap[levelStr]
+	return ok && level >= stackTraceLevel
+}
+
+// splitStackField 取出stack字段,文本格式下以缩进块的形式输出在日志行之后
+func splitStackField(fields []logger.Field) ([]logger.Field, string, bool) {
+	for i, field := range fields {
+		if field.Key != StackFieldKey {
+			continue
+		}
+		rest := make([]logger.Field, 0, len(fields)-1)
+		rest = append(rest, fields[:i]...)
+		rest = append(rest, fields[i+1:]...)
+		return rest, string(field.AppendValue(nil)), true
+	}
+	return fields, "", false
+}
+
+func appendTextStack(b []byte, stack string) []byte {
+	for _, line := range strings.Split(strings.TrimRight(stack, "\n"), "\n") {
+		b = append(b, '\t')
+		b = append(b, line...)
+		b = append(b, '\n')
+	}
+	return b
+}
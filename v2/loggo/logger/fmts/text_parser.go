@@ -0,0 +1,243 @@
+package fmts
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+// TextTimeLayout 文本格式日志的时间,不带年份
+const TextTimeLayout = "01-02T15:04:05.0000"
+
+// ErrNotTextLine 不是TraceFormatter输出的文本格式日志行
+var ErrNotTextLine = errors.New("not a text log line")
+
+var unescapeReplacer = strings.NewReplacer(
+	`\n`, "\n",
+	`\r`, "\r",
+	`\t`, "\t",
+)
+
+// Record 解析出来的一条日志
+type Record struct {
+	Time     time.Time
+	Module   string
+	Trace    string
+	Gid      int64
+	Level    logger.Level
+	FuncName string
+	FileName string
+	Line     int
+	Msg      string // 还原了\n\r\t转义的消息,包括消息之后的字段
+	Stack    string // 文本格式中日志行之后缩进的堆栈块,去掉了缩进;json/logfmt格式中的stack字段
+	Raw      string // 原始文本,包括堆栈块
+}
+
+// ParseLine 按行首判断是json、logfmt还是文本格式后解析
+func ParseLine(line string) (*Record, error) {
+	return parseLine(line, time.Now())
+}
+
+func parseLine(line string, now time.Time) (*Record, error) {
+	switch {
+	case strings.HasPrefix(line, "{"):
+		return ParseJSONLine(line)
+	case strings.HasPrefix(line, "time="):
+		return ParseLogfmtLine(line)
+	}
+	return parseTextLine(line, now)
+}
+
+// ParseTextLine 解析 [时间] [模块] [trace][gid] 级别 函数:文件:行号 消息 格式的一行日志,
+// 日志中不带年份,取离当前最近的不晚于明天的年份.颜色控制码会被去掉.
+// 消息中原本的\n等字面量与转义后的换行无法区分,都会还原成换行
+func ParseTextLine(line string) (*Record, error) {
+	return parseTextLine(line, time.Now())
+}
+
+func parseTextLine(line string, now time.Time) (*Record, error) {
+	raw := line
+	line = strings.TrimSuffix(line, "\n")
+
+	rest, ok := strings.CutPrefix(line, "[")
+	if !ok || len(rest) < len(TextTimeLayout) {
+		return nil, ErrNotTextLine
+	}
+	t, err := time.ParseInLocation(TextTimeLayout, rest[:len(TextTimeLayout)], now.Location())
+	if err != nil {
+		return nil, ErrNotTextLine
+	}
+	t = completeYear(t, now)
+
+	rest, ok = strings.CutPrefix(rest[len(TextTimeLayout):], "] [")
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+	module, rest, ok := strings.Cut(rest, "] [")
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+	trace, rest, ok := strings.Cut(rest, "][")
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+	gidStr, rest, ok := strings.Cut(rest, "] ")
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+	gid, err := strconv.ParseInt(gidStr, 10, 64)
+	if err != nil {
+		return nil, ErrNotTextLine
+	}
+
+	rest = trimColor(rest)
+	levelStr, rest, ok := strings.Cut(rest, " ")
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+	level, ok := logger.StrToLevelMap[levelStr]
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+
+	callerEnd := strings.IndexAny(rest, " \x1b")
+	if callerEnd < 0 {
+		return nil, ErrNotTextLine
+	}
+	funcName, fileName, callLine, ok := splitCaller(rest[:callerEnd])
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+	rest, ok = strings.CutPrefix(trimColor(rest[callerEnd:]), " ")
+	if !ok {
+		return nil, ErrNotTextLine
+	}
+
+	msg := rest
+	if strings.ContainsRune(msg, '\\') {
+		msg = unescapeReplacer.Replace(msg)
+	}
+
+	return &Record{
+		Time:     t,
+		Module:   module,
+		Trace:    trace,
+		Gid:      gid,
+		Level:    level,
+		FuncName: funcName,
+		FileName: fileName,
+		Line:     callLine,
+		Msg:      msg,
+		Raw:      raw,
+	}, nil
+}
+
+// completeYear 补上年份,跨年时日志时间会比当前时间晚,取上一年
+func completeYear(t, now time.Time) time.Time {
+	t = t.AddDate(now.Year()-t.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+// trimColor 去掉开头的颜色控制码
+func trimColor(s string) string {
+	for strings.HasPrefix(s, "\x1b[") {
+		end := strings.IndexByte(s, 'm')
+		if end < 0 {
+			return s
+		}
+		s = s[end+1:]
+	}
+	return s
+}
+
+// splitCaller 函数名中可能有冒号,从右边拆出文件名和行号
+func splitCaller(s string) (funcName, fileName string, line int, ok bool) {
+	lineSep := strings.LastIndexByte(s, ':')
+	if lineSep < 0 {
+		return "", "", 0, false
+	}
+	line, err := strconv.Atoi(s[lineSep+1:])
+	if err != nil {
+		return "", "", 0, false
+	}
+	fileSep := strings.LastIndexByte(s[:lineSep], ':')
+	if fileSep < 0 {
+		return "", "", 0, false
+	}
+	return s[:fileSep], s[fileSep+1 : lineSep], line, true
+}
+
+// Scanner 逐条读取文本、json或者logfmt格式的日志,同一个文件中可以混合多种格式.
+// 文本格式日志行之后以tab缩进的行作为该条日志的堆栈,无法解析的行会被跳过
+type Scanner struct {
+	r       *bufio.Reader
+	now     time.Time
+	rec     *Record
+	pending *Record // 读到的下一条日志,判断上一条的堆栈是否结束时多读了一行
+	err     error
+	skipped int
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:   bufio.NewReader(r),
+		now: time.Now(),
+	}
+}
+
+// Scan 读取下一条日志,没有更多日志或者出错时返回false
+func (s *Scanner) Scan() bool {
+	s.rec, s.pending = s.pending, nil
+	if s.err != nil {
+		return false
+	}
+
+	for {
+		line, err := s.r.ReadString('\n')
+		if len(line) > 0 {
+			if line[0] == '\t' {
+				if s.rec == nil {
+					s.skipped++
+				} else {
+					s.rec.Stack += line[1:]
+					s.rec.Raw += line
+				}
+			} else if rec, parseErr := parseLine(line, s.now); parseErr != nil {
+				s.skipped++
+			} else if s.rec == nil {
+				s.rec = rec
+			} else {
+				s.pending = rec
+				return true
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			return s.rec != nil
+		}
+	}
+}
+
+// Record 当前日志,下一次Scan之后不会再被修改
+func (s *Scanner) Record() *Record {
+	return s.rec
+}
+
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Skipped 跳过的无法解析的行数
+func (s *Scanner) Skipped() int {
+	return s.skipped
+}
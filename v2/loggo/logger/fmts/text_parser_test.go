@@ -0,0 +1,168 @@
+package fmts
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/runtimeutil"
+	simpletracectx "github.com/995933447/simpletrace/context"
+)
+
+// textCase 随机生成的一条日志,消息中不包含反斜杠,否则文本格式中与转义无法区分
+type textCase struct {
+	Format Format
+	Module string
+	Trace  string
+	Level  logger.Level
+	Color  logger.Color
+	Msg    string
+	Stack  string
+}
+
+var (
+	textCaseNameRunes = []rune("abcXYZ019-_.:/")
+	textCaseMsgRunes  = []rune("ab Z09=\"'[]:{}\n\r\t\x1b日志é")
+	textCaseLevels    = []logger.Level{logger.LevelDebug, logger.LevelInfo, logger.LevelImportant, logger.LevelWarn, logger.LevelError, logger.LevelPanic, logger.LevelFatal}
+	textCaseColors    = []logger.Color{logger.ColorNil, logger.ColorRed, logger.ColorBlue, logger.ColorPurple}
+	textCaseFormats   = []Format{FormatText, FormatJSON, FormatLogfmt}
+)
+
+func randString(r *rand.Rand, runes []rune, maxLen int) string {
+	var b strings.Builder
+	for i := r.Intn(maxLen + 1); i > 0; i-- {
+		b.WriteRune(runes[r.Intn(len(runes))])
+	}
+	return b.String()
+}
+
+func (textCase) Generate(r *rand.Rand, size int) reflect.Value {
+	c := textCase{
+		Format: textCaseFormats[r.Intn(len(textCaseFormats))],
+		Module: randString(r, textCaseNameRunes, 8),
+		Trace:  randString(r, textCaseNameRunes, 16),
+		Level:  textCaseLevels[r.Intn(len(textCaseLevels))],
+		Color:  textCaseColors[r.Intn(len(textCaseColors))],
+		Msg:    randString(r, textCaseMsgRunes, size),
+	}
+	for i := r.Intn(3); i > 0; i-- {
+		c.Stack += "pkg." + randString(r, textCaseNameRunes, 8) + "\n\t/src/" + randString(r, textCaseNameRunes, 8) + ".go:12\n"
+	}
+	return reflect.ValueOf(c)
+}
+
+func TestParseLineRoundTrip(t *testing.T) {
+	cfgLoader, err := logger.NewConfLoader("", 10, &logger.LogConf{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, _, _, _ := runtime.Caller(0)
+	expectFile, expectFunc, expectLine := lookupCaller(pc)
+	_, expectGid := runtimeutil.GetTraceWithGidDefNoTrace()
+
+	roundTrip := func(c textCase) bool {
+		f := NewTraceFormatter(c.Module, 1, c.Format, false, false, cfgLoader)
+		extra := &logger.Extra{PC: pc}
+		if c.Trace != "" {
+			extra.Ctx = simpletracectx.New("test", context.Background(), c.Trace, "s1")
+		}
+		if c.Stack != "" {
+			extra.Fields = []logger.Field{logger.String(StackFieldKey, c.Stack)}
+		}
+		before := time.Now().Truncate(100 * time.Microsecond)
+		line, err := f.Sprintf(c.Level, c.Color, c.Msg, extra)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		after := time.Now()
+
+		scanner := NewScanner(strings.NewReader(string(line)))
+		if !scanner.Scan() {
+			t.Logf("no record scanned from %q", line)
+			return false
+		}
+		rec := scanner.Record()
+		expectTrace := c.Trace
+		if expectTrace == "" {
+			expectTrace, _ = runtimeutil.GetTraceWithGidDefNoTrace()
+		}
+		ok := rec.Module == c.Module && rec.Trace == expectTrace && rec.Gid == expectGid && rec.Level == c.Level &&
+			rec.FuncName == expectFunc && rec.FileName == expectFile && rec.Line == expectLine &&
+			rec.Msg == c.Msg && rec.Stack == c.Stack && rec.Raw == string(line) &&
+			!rec.Time.Before(before) && !rec.Time.After(after) && !scanner.Scan() && scanner.Skipped() == 0
+		if !ok {
+			t.Logf("line %q parsed as %+v", line, rec)
+		}
+		return ok
+	}
+	if err = quick.Check(roundTrip, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	content := "[01-02T15:04:05.0001] [app] [t1][7] \x1b[91mERR main.main:main.go:10\x1b[0m failed\\nretry uid=1\n" +
+		"\tmain.main\n" +
+		"\t\t/src/main.go:10\n" +
+		"[01-02T15:04:06.0000] dropped 3 lines because log buffer is full\n" +
+		"[01-02T15:04:07.1234] [] [notrace][8] INFO (*T).Run:t.go:20 \n" +
+		`{"time":"2025-01-02T15:04:08.000001+08:00","module":"app","trace":"t2","gid":9,"level":"WARN","caller":"main.run:main.go:30","msg":"slow","cost":1.5,"sql":"select 1"}` + "\n" +
+		`time=2025-01-02T15:04:09.000001+08:00 level=INFO module=app trace=t3 gid=10 caller=main.run:main.go:40 msg="a b" uid=2 stack="main.run\n"` + "\n"
+	scanner := NewScanner(strings.NewReader(content))
+
+	if !scanner.Scan() {
+		t.Fatal("expect first record")
+	}
+	rec := scanner.Record()
+	if rec.Module != "app" || rec.Trace != "t1" || rec.Gid != 7 || rec.Level != logger.LevelError ||
+		rec.FuncName != "main.main" || rec.FileName != "main.go" || rec.Line != 10 ||
+		rec.Msg != "failed\nretry uid=1" || rec.Stack != "main.main\n\t/src/main.go:10\n" {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	if rec.Time.Month() != time.January || rec.Time.Day() != 2 || rec.Time.Nanosecond() != 100000 {
+		t.Fatalf("unexpected time %s", rec.Time)
+	}
+
+	if !scanner.Scan() {
+		t.Fatal("expect second record")
+	}
+	if rec = scanner.Record(); rec.Module != "" || rec.FuncName != "(*T).Run" || rec.Msg != "" || rec.Stack != "" {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+
+	// json和logfmt中额外的字段与文本格式一样追加在消息之后
+	if !scanner.Scan() {
+		t.Fatal("expect json record")
+	}
+	if rec = scanner.Record(); rec.Trace != "t2" || rec.Gid != 9 || rec.Level != logger.LevelWarn || rec.FuncName != "main.run" ||
+		rec.Line != 30 || rec.Msg != `slow cost=1.5 sql="select 1"` || rec.Time.Nanosecond() != 1000 {
+		t.Fatalf("unexpected json record %+v", rec)
+	}
+	if !scanner.Scan() {
+		t.Fatal("expect logfmt record")
+	}
+	if rec = scanner.Record(); rec.Trace != "t3" || rec.Gid != 10 || rec.Level != logger.LevelInfo || rec.Line != 40 ||
+		rec.Msg != "a b uid=2" || rec.Stack != "main.run\n" {
+		t.Fatalf("unexpected logfmt record %+v", rec)
+	}
+	if scanner.Scan() || scanner.Err() != nil || scanner.Skipped() != 1 {
+		t.Fatalf("expect end of scan, skipped:%d err:%v", scanner.Skipped(), scanner.Err())
+	}
+}
+
+func TestCompleteYear(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 10, 0, 0, time.Local)
+	if got := completeYear(time.Date(0, 12, 31, 23, 59, 0, 0, time.Local), now); got.Year() != 2024 {
+		t.Fatalf("expect last year, got %s", got)
+	}
+	if got := completeYear(time.Date(0, 1, 1, 0, 9, 0, 0, time.Local), now); got.Year() != 2025 {
+		t.Fatalf("expect this year, got %s", got)
+	}
+}
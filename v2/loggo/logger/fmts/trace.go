@@ -1,6 +1,8 @@
 package fmts
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
@@ -21,6 +23,19 @@ type caller struct {
 
 var callerCache sync.Map
 
+// record 结构化格式(json/logfmt)编码一行日志所需的信息
+type record struct {
+	levelStr   string
+	moduleName string
+	trace      string
+	gid        int64
+	funcName   string
+	fileName   string
+	line       int
+	msg        string
+	fields     []logger.Field
+}
+
 var _ logger.Formatter = (*TraceFormatter)(nil)
 
 var replacer = strings.NewReplacer(
@@ -66,28 +81,46 @@ func (f *TraceFormatter) Copy() logger.Formatter {
 }
 
 func (f *TraceFormatter) Sprintf(level logger.Level, stdoutColor logger.Color, args ...interface{}) ([]byte, error) {
+	return f.AppendFormat(nil, level, stdoutColor, f.skipCall+1, args...)
+}
+
+// AppendFormat 格式化日志追加到b后返回,skipCall从AppendFormat的调用方算起.
+// 不需要为不同的skipCall复制formatter,配合复用的b常规日志不会产生内存分配
+func (f *TraceFormatter) AppendFormat(b []byte, level logger.Level, stdoutColor logger.Color, skipCall int, args ...interface{}) ([]byte, error) {
+	args, extra := logger.SplitExtra(args)
+	var (
+		fields   []logger.Field
+		ctx      context.Context
+		callerPC uintptr
+	)
+	if extra != nil {
+		fields = extra.Fields
+		ctx = extra.Ctx
+		callerPC = extra.PC
+	}
+
 	levelStr, err := logger.TransferLevelToStr(level)
 	if err != nil {
-		return nil, err
+		return b, err
 	}
 
 	var colorStdoutStart, colorStdoutEnd string
 	if !f.disabledStdoutColor && stdoutColor != logger.ColorNil {
 		colorStdoutStart, err = logger.GetColorStdout(stdoutColor)
 		if err != nil {
-			return nil, err
+			return b, err
 		}
 		colorStdoutEnd = logger.ColorToStdoutMap[logger.ColorNil]
 	}
 
 	var fileName, callFuncName string
 	var callLine int
-	if f.disabledCacheCaller {
+	if f.disabledCacheCaller && callerPC == 0 {
 		var (
 			pc uintptr
 			ok bool
 		)
-		pc, fileName, callLine, ok = runtime.Caller(f.skipCall)
+		pc, fileName, callLine, ok = runtime.Caller(skipCall)
 		if ok {
 			callFuncName = runtime.FuncForPC(pc).Name()
 		}
@@ -97,32 +130,22 @@ func (f *TraceFormatter) Sprintf(level logger.Level, stdoutColor logger.Color, a
 			fileName = fileName[lastSlash+1:]
 		}
 	} else {
-		rpc := make([]uintptr, 1)
-		n := runtime.Callers(f.skipCall+1, rpc)
+		var rpc [1]uintptr
+		n := 1
+		if callerPC != 0 {
+			rpc[0] = callerPC
+		} else {
+			n = runtime.Callers(skipCall+1, rpc[:])
+		}
 		if n > 0 {
-			pc := rpc[0]
-			callAny, ok := callerCache.Load(pc)
-			if ok {
-				call := callAny.(*caller)
-				fileName, callLine, callFuncName = call.fileName, call.line, call.funcName
-			} else {
-				frame, _ := runtime.CallersFrames(rpc).Next()
-				fileName = frame.File
-				callFuncName = frame.Function
-				callLine = frame.Line
-				lastSlash := strings.LastIndexByte(fileName, '/')
-				if lastSlash >= 0 {
-					fileName = fileName[lastSlash+1:]
-				}
-				callerCache.Store(pc, &caller{
-					fileName: fileName,
-					line:     callLine,
-					funcName: callFuncName,
-				})
-			}
+			fileName, callFuncName, callLine = lookupCaller(rpc[0])
 		}
 	}
 
+	// 格式化后的消息先写入复用的临时缓冲
+	msgBuf := getMsgBuf()
+	defer putMsgBuf(msgBuf)
+
 	var rawFormatted string
 	argsNum := len(args)
 	if argsNum > 0 {
@@ -135,15 +158,35 @@ func (f *TraceFormatter) Sprintf(level logger.Level, stdoutColor logger.Color, a
 		case error:
 			rawFormatted = v.Error()
 		default:
-			rawFormatted = fmt.Sprint(firstArg)
+			if argsNum > 1 {
+				// 作为format时不能和格式化结果共用缓冲
+				rawFormatted = fmt.Sprint(firstArg)
+				break
+			}
+			msgBuf.b = fmt.Append(msgBuf.b[:0], firstArg)
+			rawFormatted = msgBuf.String()
 		}
 		if argsNum > 1 {
-			rawFormatted = fmt.Sprintf(rawFormatted, args[1:]...)
+			msgBuf.b = fmt.Appendf(msgBuf.b[:0], rawFormatted, args[1:]...)
+			rawFormatted = msgBuf.String()
 		}
+	}
+
+	formatType := f.getFormatType()
+	if formatType == FormatText && strings.ContainsAny(rawFormatted, "\n\r\t") {
 		rawFormatted = replacer.Replace(rawFormatted)
 	}
 
 	trace, gid := runtimeutil.GetTraceWithGidDefNoTrace()
+	if ctxTrace, ok := logger.ExtractTrace(ctx); ok {
+		trace = ctxTrace
+	}
+
+	fields, stack, hasStack := splitStackField(fields)
+	if !hasStack && f.needStackTrace(level) {
+		stack = captureStack(skipCall, callerPC, int(f.cfgLoader.GetConf().File.StackTraceMaxDepth))
+		hasStack = stack != ""
+	}
 
 	switch level {
 	case logger.LevelDebug:
@@ -158,19 +201,27 @@ func (f *TraceFormatter) Sprintf(level logger.Level, stdoutColor logger.Color, a
 		}
 	}
 
-	callLineStr := strconv.Itoa(callLine)
-	// App id & Uid & Gid
-	var buf [19]byte
-	bb := buf[:0]
-	bb = strconv.AppendInt(bb, gid, 10)
-
-	if f.formatType == FormatText {
-		// 预估分配
-		msgLen := 40 + len(rawFormatted) + len(f.moduleName) + len(trace) +
-			len(colorStdoutStart) + len(colorStdoutEnd) + len(levelStr) + len(fileName) +
-			len(callFuncName) + len(callLineStr) + len(bb)
-		b := make([]byte, 0, msgLen)
-
+	switch formatType {
+	case FormatJSON, FormatLogfmt:
+		if hasStack {
+			fields = append(fields[:len(fields):len(fields)], logger.String(StackFieldKey, stack))
+		}
+		r := record{
+			levelStr:   levelStr,
+			moduleName: f.moduleName,
+			trace:      trace,
+			gid:        gid,
+			funcName:   callFuncName,
+			fileName:   fileName,
+			line:       callLine,
+			msg:        rawFormatted,
+			fields:     fields,
+		}
+		if formatType == FormatJSON {
+			return appendJSONLine(b, &r), nil
+		}
+		return appendLogfmtLine(b, &r), nil
+	case FormatText:
 		// Timestamp
 		b = append(b, '[')
 		b = append(b, runtimeutil.GetNowFormatFast()...)
@@ -196,7 +247,7 @@ func (f *TraceFormatter) Sprintf(level logger.Level, stdoutColor logger.Color, a
 		b = append(b, "]["...)
 
 		// Gid
-		b = append(b, bb...)
+		b = strconv.AppendInt(b, gid, 10)
 
 		b = append(b, "] "...)
 
@@ -210,18 +261,70 @@ func (f *TraceFormatter) Sprintf(level logger.Level, stdoutColor logger.Color, a
 		b = append(b, ':')
 		b = append(b, fileName...)
 		b = append(b, ':')
-		b = append(b, callLineStr...)
+		b = strconv.AppendInt(b, int64(callLine), 10)
 		b = append(b, colorStdoutEnd...)
 
 		// Log message
 		b = append(b, ' ')
 		b = append(b, rawFormatted...)
+
+		// Fields
+		b = appendTextFields(b, fields)
 		b = append(b, '\n')
 
+		// Stack
+		if hasStack {
+			b = appendTextStack(b, stack)
+		}
+
 		return b, nil
 	}
 
-	return nil, errors.New("not support log format")
+	return b, errors.New("not support log format")
+}
+
+// lookupCaller 按pc查找调用方信息,结果缓存
+func lookupCaller(pc uintptr) (fileName, funcName string, line int) {
+	if callAny, ok := callerCache.Load(pc); ok {
+		call := callAny.(*caller)
+		return call.fileName, call.funcName, call.line
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	fileName = frame.File
+	lastSlash := strings.LastIndexByte(fileName, '/')
+	if lastSlash >= 0 {
+		fileName = fileName[lastSlash+1:]
+	}
+	callerCache.Store(pc, &caller{
+		fileName: fileName,
+		line:     frame.Line,
+		funcName: frame.Function,
+	})
+
+	return fileName, frame.Function, frame.Line
+}
+
+// getFormatType 配置中指定了格式则优先使用配置,支持热更新切换
+func (f *TraceFormatter) getFormatType() Format {
+	if formatType, ok := TransStrToFormat(f.cfgLoader.GetConf().File.Format); ok {
+		return formatType
+	}
+	return f.formatType
+}
+
+func appendTextFields(b []byte, fields []logger.Field) []byte {
+	for _, field := range fields {
+		b = append(b, ' ')
+		b = append(b, field.Key...)
+		b = append(b, '=')
+		start := len(b)
+		b = field.AppendValue(b)
+		if val := b[start:]; len(val) == 0 || bytes.ContainsAny(val, " =\"\n\r\t") {
+			b = strconv.AppendQuote(b[:start], string(val))
+		}
+	}
+	return b
 }
 
 func (f *TraceFormatter) truncateByRunes(s string, maxLen int32) string {
@@ -0,0 +1,168 @@
+package fmts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	simpletracectx "github.com/995933447/simpletrace/context"
+)
+
+func newTestFormatter(t *testing.T, formatType Format) *TraceFormatter {
+	cfgLoader, err := logger.NewConfLoader("", 10, &logger.LogConf{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTraceFormatter("test", 1, formatType, true, true, cfgLoader)
+}
+
+func TestTextFields(t *testing.T) {
+	f := newTestFormatter(t, FormatText)
+	b, err := f.Sprintf(logger.LevelInfo, logger.ColorNil, "order %s paid", "A1", &logger.Extra{
+		Fields: []logger.Field{
+			logger.Int("uid", 10),
+			logger.String("note", "a b"),
+			logger.Err(errors.New("x=y")),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(b)
+	if !strings.HasSuffix(line, ` order A1 paid uid=10 note="a b" err="x=y"`+"\n") {
+		t.Fatalf("unexpected line: %s", line)
+	}
+}
+
+func TestJSONLine(t *testing.T) {
+	f := newTestFormatter(t, FormatJSON)
+	b, err := f.Sprintf(logger.LevelWarn, logger.ColorRed, "bad \"input\"\n\t%s", "\x01", &logger.Extra{
+		Fields: []logger.Field{
+			logger.Int("uid", 10),
+			logger.Bool("retry", true),
+			logger.Any("tags", []string{"a", "b"}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line map[string]interface{}
+	if err = json.Unmarshal(b, &line); err != nil {
+		t.Fatalf("invalid json %s: %v", b, err)
+	}
+	if line["msg"] != "bad \"input\"\n\t\x01" || line["level"] != "WARN" || line["module"] != "test" {
+		t.Fatalf("unexpected line: %s", b)
+	}
+	if line["uid"] != float64(10) || line["retry"] != true || len(line["tags"].([]interface{})) != 2 {
+		t.Fatalf("unexpected fields: %s", b)
+	}
+}
+
+func TestLogfmtLine(t *testing.T) {
+	f := newTestFormatter(t, FormatLogfmt)
+	b, err := f.Sprintf(logger.LevelInfo, logger.ColorNil, "user login", &logger.Extra{
+		Fields: []logger.Field{
+			logger.String("ip", "10.0.0.1"),
+			logger.String("query", "a=1 b=2"),
+			logger.String("empty", ""),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(b)
+	if !strings.Contains(line, " level=INFO module=test ") {
+		t.Fatalf("unexpected line: %s", line)
+	}
+	if !strings.HasSuffix(line, ` msg="user login" ip=10.0.0.1 query="a=1 b=2" empty=""`+"\n") {
+		t.Fatalf("unexpected line: %s", line)
+	}
+}
+
+func TestCtxTrace(t *testing.T) {
+	f := newTestFormatter(t, FormatLogfmt)
+	ctx := logger.ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	b, err := f.Sprintf(logger.LevelInfo, logger.ColorNil, "hello", &logger.Extra{Ctx: ctx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), " trace=4bf92f3577b34da6a3ce929d0e0e4736 ") {
+		t.Fatalf("unexpected line: %s", b)
+	}
+
+	ctx = simpletracectx.New("test", context.Background(), "t123", "s456")
+	b, err = f.Sprintf(logger.LevelInfo, logger.ColorNil, "hello", &logger.Extra{Ctx: ctx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), " trace=t123 ") {
+		t.Fatalf("unexpected line: %s", b)
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	cfgLoader, err := logger.NewConfLoader("", 10, &logger.LogConf{File: logger.FileLogConf{StackTraceLevel: "ERR", StackTraceMaxDepth: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewTraceFormatter("test", 1, FormatText, true, false, cfgLoader)
+
+	b, err := f.Sprintf(logger.LevelWarn, logger.ColorNil, "no stack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), "\n") != 1 {
+		t.Fatalf("unexpected stack: %s", b)
+	}
+
+	b, err = f.Sprintf(logger.LevelError, logger.ColorNil, "with stack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 3 || lines[1] != "\tgithub.com/995933447/log-go/v2/loggo/logger/fmts.TestStackTrace" ||
+		!strings.HasPrefix(lines[2], "\t\t") || !strings.Contains(lines[2], "trace_test.go:") {
+		t.Fatalf("unexpected stack: %s", b)
+	}
+}
+
+func BenchmarkAppendFormatText(b *testing.B) {
+	cfgLoader, err := logger.NewConfLoader("", 10, &logger.LogConf{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	f := NewTraceFormatter("test", 1, FormatText, true, false, cfgLoader)
+	args := []interface{}{"order %s paid %d", "A1", 100, &logger.Extra{
+		Fields: []logger.Field{logger.Int("uid", 10), logger.String("note", "a b")},
+	}}
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if buf, err = f.AppendFormat(buf[:0], logger.LevelInfo, logger.ColorNil, 1, args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendFormatJSON(b *testing.B) {
+	cfgLoader, err := logger.NewConfLoader("", 10, &logger.LogConf{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	f := NewTraceFormatter("test", 1, FormatJSON, true, false, cfgLoader)
+	args := []interface{}{"order %s paid %d", "A1", 100, &logger.Extra{
+		Fields: []logger.Field{logger.Int("uid", 10), logger.String("note", "a b")},
+	}}
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if buf, err = f.AppendFormat(buf[:0], logger.LevelInfo, logger.ColorNil, 1, args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LevelOverrideConf 按调用方或者logger覆盖日志最小级别
+type LevelOverrideConf struct {
+	Callers map[string]string // 函数/包名前缀 => 级别,最长前缀优先,前缀只在包路径和函数名的边界处匹配
+	Loggers map[string]string // logger名(writer的文件前缀) => 级别
+}
+
+type callerLevelPrefix struct {
+	prefix string
+	level  Level
+}
+
+// LevelOverrides 由LevelOverrideConf编译而来,随配置热更新整体替换
+type LevelOverrides struct {
+	loggerLevels   map[string]Level
+	callerPrefixes []callerLevelPrefix
+	minCallerLevel Level
+	pcLevels       sync.Map // uintptr => *pcLevel
+}
+
+type pcLevel struct {
+	level   Level
+	matched bool
+}
+
+func NewLevelOverrides(cfg *LevelOverrideConf) *LevelOverrides {
+	if cfg == nil || (len(cfg.Callers) == 0 && len(cfg.Loggers) == 0) {
+		return nil
+	}
+
+	overrides := &LevelOverrides{
+		loggerLevels:   make(map[string]Level, len(cfg.Loggers)),
+		minCallerLevel: LevelFatal,
+	}
+	for name, levelStr := range cfg.Loggers {
+		if level, ok := StrToLevelMap[levelStr]; ok {
+			overrides.loggerLevels[name] = level
+		}
+	}
+	for prefix, levelStr := range cfg.Callers {
+		level, ok := StrToLevelMap[levelStr]
+		if !ok || prefix == "" {
+			continue
+		}
+		overrides.callerPrefixes = append(overrides.callerPrefixes, callerLevelPrefix{prefix: prefix, level: level})
+		if level < overrides.minCallerLevel {
+			overrides.minCallerLevel = level
+		}
+	}
+	sort.Slice(overrides.callerPrefixes, func(i, j int) bool {
+		return len(overrides.callerPrefixes[i].prefix) > len(overrides.callerPrefixes[j].prefix)
+	})
+
+	return overrides
+}
+
+func (o *LevelOverrides) GetLoggerLevel(name string) (Level, bool) {
+	if o == nil {
+		return 0, false
+	}
+	level, ok := o.loggerLevels[name]
+	return level, ok
+}
+
+func (o *LevelOverrides) HasCallerOverrides() bool {
+	return o != nil && len(o.callerPrefixes) > 0
+}
+
+// GetMinCallerLevel 所有调用方覆盖中最低的级别,低于它且低于全局级别的日志可以不解析调用方直接丢弃
+func (o *LevelOverrides) GetMinCallerLevel() Level {
+	return o.minCallerLevel
+}
+
+// GetCallerLevel 按调用方pc匹配覆盖级别,结果按pc缓存
+func (o *LevelOverrides) GetCallerLevel(pc uintptr) (Level, bool) {
+	if !o.HasCallerOverrides() {
+		return 0, false
+	}
+
+	if cached, ok := o.pcLevels.Load(pc); ok {
+		l := cached.(*pcLevel)
+		return l.level, l.matched
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	l := &pcLevel{}
+	for _, p := range o.callerPrefixes {
+		if matchCallerPrefix(frame.Function, p.prefix) {
+			l.level, l.matched = p.level, true
+			break
+		}
+	}
+	o.pcLevels.Store(pc, l)
+
+	return l.level, l.matched
+}
+
+// matchCallerPrefix prefix等于function,或者function在prefix之后是/或.时匹配,pkg/foo不匹配pkg/foobar
+func matchCallerPrefix(function, prefix string) bool {
+	if !strings.HasPrefix(function, prefix) {
+		return false
+	}
+	if len(function) == len(prefix) || strings.HasSuffix(prefix, "/") || strings.HasSuffix(prefix, ".") {
+		return true
+	}
+	next := function[len(prefix)]
+	return next == '/' || next == '.'
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestLevelOverrides(t *testing.T) {
+	overrides := NewLevelOverrides(&LevelOverrideConf{
+		Callers: map[string]string{
+			"github.com/995933447/log-go/v2/loggo":        "ERR",
+			"github.com/995933447/log-go/v2/loggo/logger": "DBG",
+		},
+		Loggers: map[string]string{
+			"bill": "WARN",
+			"bad":  "UNKNOWN",
+		},
+	})
+
+	if level, ok := overrides.GetLoggerLevel("bill"); !ok || level != LevelWarn {
+		t.Fatalf("unexpected bill level %d", level)
+	}
+	if _, ok := overrides.GetLoggerLevel("bad"); ok {
+		t.Fatal("unknown level should be ignored")
+	}
+	if overrides.GetMinCallerLevel() != LevelDebug {
+		t.Fatalf("unexpected min caller level %d", overrides.GetMinCallerLevel())
+	}
+
+	var rpc [1]uintptr
+	runtime.Callers(1, rpc[:])
+	for i := 0; i < 2; i++ {
+		if level, ok := overrides.GetCallerLevel(rpc[0]); !ok || level != LevelDebug {
+			t.Fatalf("longest prefix should win, got %d", level)
+		}
+	}
+
+	if NewLevelOverrides(&LevelOverrideConf{}) != nil {
+		t.Fatal("empty conf should compile to nil")
+	}
+}
+
+func TestMatchCallerPrefix(t *testing.T) {
+	tests := []struct {
+		function string
+		prefix   string
+		match    bool
+	}{
+		{"pkg/foo.Func", "pkg/foo", true},
+		{"pkg/foo/sub.Func", "pkg/foo", true},
+		{"pkg/foo.(*T).Method", "pkg/foo.(*T)", true},
+		{"pkg/foo.Func.func1", "pkg/foo.Func", true},
+		{"pkg/foo.Func", "pkg/foo.Func", true},
+		{"pkg/foo/sub.Func", "pkg/foo/", true},
+		{"pkg/foobar.Func", "pkg/foo", false},
+		{"pkg/foo.FuncBar", "pkg/foo.Func", false},
+		{"pkg/fo", "pkg/foo", false},
+	}
+	for _, tt := range tests {
+		if match := matchCallerPrefix(tt.function, tt.prefix); match != tt.match {
+			t.Fatalf("matchCallerPrefix(%s, %s) = %v, want %v", tt.function, tt.prefix, match, tt.match)
+		}
+	}
+
+	overrides := NewLevelOverrides(&LevelOverrideConf{
+		Callers: map[string]string{"github.com/995933447/log-go/v2/loggo/logger.TestMatchCaller": "DBG"},
+	})
+	var rpc [1]uintptr
+	runtime.Callers(1, rpc[:])
+	if _, ok := overrides.GetCallerLevel(rpc[0]); ok {
+		t.Fatal("prefix should not match in the middle of a function name")
+	}
+}
+
+func TestLoadLevelOverrideFromFile(t *testing.T) {
+	cfgFile := t.TempDir() + "/log.toml"
+	if err := os.WriteFile(cfgFile, []byte("[File]\nLevel = \"INFO\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loader, err := NewConfLoader(cfgFile, 3600, &LogConf{
+		LevelOverride: LevelOverrideConf{Loggers: map[string]string{"bill": "WARN"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loader.GetLevelOverrides().GetLoggerLevel("bill"); !ok {
+		t.Fatal("default overrides should be used when file has no LevelOverride")
+	}
+
+	// 热更新写入空的LevelOverride清除覆盖
+	if err = os.WriteFile(cfgFile, []byte("[File]\nLevel = \"INFO\"\n[LevelOverride]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = loader.loadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if loader.GetLevelOverrides() != nil {
+		t.Fatal("empty LevelOverride in file should clear overrides")
+	}
+}
@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const lineWriterMaxCallerDepth = 16
+
+var _ io.Writer = (*LineWriter)(nil)
+
+// LineWriter 把写入的内容按行以指定级别写入Logger,用于接管标准库log以及只接受io.Writer的第三方库输出
+type LineWriter struct {
+	getLogger func() *Logger
+	level     Level
+	buf       []byte
+	mu        sync.Mutex
+}
+
+func NewLineWriter(logger *Logger, level Level) *LineWriter {
+	return NewLineWriterFunc(func() *Logger {
+		return logger
+	}, level)
+}
+
+// NewLineWriterFunc 每次写入时通过getLogger取Logger,用于Logger可能还没初始化或者会重新初始化的场景,
+// 取到nil时输出到标准错误
+func NewLineWriterFunc(getLogger func() *Logger, level Level) *LineWriter {
+	return &LineWriter{
+		getLogger: getLogger,
+		level:     level,
+	}
+}
+
+// NewStdLog 返回按指定级别写入Logger的标准库*log.Logger,例如http.Server.ErrorLog
+func NewStdLog(logger *Logger, level Level) *log.Logger {
+	return log.New(NewLineWriter(logger, level), "", 0)
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var pc uintptr
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		if pc == 0 {
+			pc = lineWriterCallerPC()
+		}
+		w.writeLine(w.buf[:idx], pc)
+		w.buf = w.buf[idx+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+
+	return len(p), nil
+}
+
+// Sync 写出缓冲中不以换行结尾的剩余内容
+func (w *LineWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.writeLine(w.buf, lineWriterCallerPC())
+		w.buf = nil
+	}
+
+	return nil
+}
+
+func (w *LineWriter) writeLine(line []byte, pc uintptr) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 {
+		return
+	}
+	logger := w.getLogger()
+	if logger == nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s\n", line)
+		return
+	}
+	if err := logger.Write(w.level, string(line), &Extra{PC: pc}); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// lineWriterCallerPC 跳过LineWriter和标准库log/fmt内部的调用,找到真正写日志的调用方
+func lineWriterCallerPC() uintptr {
+	var rpc [lineWriterMaxCallerDepth]uintptr
+	n := runtime.Callers(2, rpc[:])
+	for _, pc := range rpc[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if strings.HasPrefix(frame.Function, "log.") ||
+			strings.HasPrefix(frame.Function, "fmt.") ||
+			strings.HasPrefix(frame.Function, "io.") ||
+			strings.HasPrefix(frame.Function, "bufio.") ||
+			strings.Contains(frame.Function, "/loggo/logger.(*LineWriter)") {
+			continue
+		}
+		return pc
+	}
+	return 0
+}
@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"strings"
 	"sync"
 )
 
@@ -14,10 +17,18 @@ type Formatter interface {
 	Sprintf(level Level, stdoutColor Color, args ...interface{}) ([]byte, error)
 }
 
+// AppendFormatter Formatter可选实现的接口,writer格式化到复用的缓冲区,避免每行日志分配内存
+type AppendFormatter interface {
+	// AppendFormat 格式化追加到b,skipCall从AppendFormat的调用方算起
+	AppendFormat(b []byte, level Level, stdoutColor Color, skipCall int, args ...interface{}) ([]byte, error)
+}
+
 type Msg struct {
 	Level     Level
 	SkipCall  int
+	PC        uintptr // 调用方pc,WriteMsg按它匹配调用方级别覆盖和采样
 	Formatted []byte
+	Fields    []Field
 }
 
 type Writer interface {
@@ -36,6 +47,7 @@ type Writer interface {
 
 type Logger struct {
 	writer Writer
+	fields []Field
 	mu     sync.RWMutex
 }
 
@@ -45,6 +57,21 @@ func NewLogger(writer Writer) *Logger {
 	}
 }
 
+// With 返回绑定了fields的子Logger,子Logger输出的每行日志都会带上这些字段
+func (l *Logger) With(fields ...Field) *Logger {
+	child := &Logger{
+		writer: l.writer,
+	}
+	child.fields = make([]Field, 0, len(l.fields)+len(fields))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	return child
+}
+
+func (l *Logger) GetFields() []Field {
+	return l.fields
+}
+
 func (l *Logger) EnableStdoutPrinter() {
 	l.writer.EnableStdoutPrinter()
 }
@@ -87,10 +114,31 @@ func (l *Logger) Error(content interface{}) {
 	}
 }
 
+// panicValue content是error时原样panic,recover之后可以用errors.As取出
+func panicValue(content interface{}) interface{} {
+	if err, ok := content.(error); ok {
+		return err
+	}
+	return fmt.Sprint(content)
+}
+
+// panicfValue format中有%w时以包装后的error panic
+func panicfValue(format string, args []interface{}) interface{} {
+	if strings.Contains(format, "%w") {
+		return fmt.Errorf(format, args...)
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Panic 写入日志并flush后以消息内容panic,content是error时以error本身panic
 func (l *Logger) Panic(content interface{}) {
 	if err := l.Write(LevelPanic, content); err != nil {
 		fmt.Println(err)
 	}
+	if err := l.Flush(); err != nil {
+		fmt.Println(err)
+	}
+	panic(panicValue(content))
 }
 
 func (l *Logger) Fatal(content interface{}) {
@@ -104,43 +152,147 @@ func (l *Logger) Fatal(content interface{}) {
 }
 
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	if err := l.Write(LevelDebug, append([]interface{}{format}, args...)...); err != nil {
+	if err := l.writef(nil, LevelDebug, format, args); err != nil {
 		fmt.Println(err)
 	}
 }
 
 func (l *Logger) Infof(format string, args ...interface{}) {
-	if err := l.Write(LevelInfo, append([]interface{}{format}, args...)...); err != nil {
+	if err := l.writef(nil, LevelInfo, format, args); err != nil {
 		fmt.Println(err)
 	}
 }
 
 func (l *Logger) Importantf(format string, args ...interface{}) {
-	if err := l.Write(LevelImportant, append([]interface{}{format}, args...)...); err != nil {
+	if err := l.writef(nil, LevelImportant, format, args); err != nil {
 		fmt.Println(err)
 	}
 }
 
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	if err := l.Write(LevelWarn, append([]interface{}{format}, args...)...); err != nil {
+	if err := l.writef(nil, LevelWarn, format, args); err != nil {
 		fmt.Println(err)
 	}
 }
 
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	if err := l.Write(LevelError, append([]interface{}{format}, args...)...); err != nil {
+	if err := l.writef(nil, LevelError, format, args); err != nil {
 		fmt.Println(err)
 	}
 }
 
 func (l *Logger) Panicf(format string, args ...interface{}) {
-	if err := l.Write(LevelPanic, append([]interface{}{format}, args...)...); err != nil {
+	if err := l.writef(nil, LevelPanic, format, args); err != nil {
+		fmt.Println(err)
+	}
+	if err := l.Flush(); err != nil {
 		fmt.Println(err)
 	}
+	panic(panicfValue(format, args))
 }
 
 func (l *Logger) Fatalf(format string, args ...interface{}) {
-	if err := l.Write(LevelFatal, append([]interface{}{format}, args...)...); err != nil {
+	if err := l.writef(nil, LevelFatal, format, args); err != nil {
+		fmt.Println(err)
+	}
+	if err := l.Flush(); err != nil {
+		fmt.Println(err)
+	}
+	log.Fatalf(format, args...)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, content interface{}) {
+	if err := l.WriteCtx(ctx, LevelDebug, content); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, content interface{}) {
+	if err := l.WriteCtx(ctx, LevelInfo, content); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) ImportantCtx(ctx context.Context, content interface{}) {
+	if err := l.WriteCtx(ctx, LevelImportant, content); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, content interface{}) {
+	if err := l.WriteCtx(ctx, LevelWarn, content); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, content interface{}) {
+	if err := l.WriteCtx(ctx, LevelError, content); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) PanicCtx(ctx context.Context, content interface{}) {
+	if err := l.WriteCtx(ctx, LevelPanic, content); err != nil {
+		fmt.Println(err)
+	}
+	if err := l.Flush(); err != nil {
+		fmt.Println(err)
+	}
+	panic(panicValue(content))
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, content interface{}) {
+	if err := l.WriteCtx(ctx, LevelFatal, content); err != nil {
+		fmt.Println(err)
+	}
+	if err := l.Flush(); err != nil {
+		fmt.Println(err)
+	}
+	log.Fatal(content)
+}
+
+func (l *Logger) DebugfCtx(ctx context.Context, format string, args ...interface{}) {
+	if err := l.writef(ctx, LevelDebug, format, args); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	if err := l.writef(ctx, LevelInfo, format, args); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) ImportantfCtx(ctx context.Context, format string, args ...interface{}) {
+	if err := l.writef(ctx, LevelImportant, format, args); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) WarnfCtx(ctx context.Context, format string, args ...interface{}) {
+	if err := l.writef(ctx, LevelWarn, format, args); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	if err := l.writef(ctx, LevelError, format, args); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (l *Logger) PanicfCtx(ctx context.Context, format string, args ...interface{}) {
+	if err := l.writef(ctx, LevelPanic, format, args); err != nil {
+		fmt.Println(err)
+	}
+	if err := l.Flush(); err != nil {
+		fmt.Println(err)
+	}
+	panic(panicfValue(format, args))
+}
+
+func (l *Logger) FatalfCtx(ctx context.Context, format string, args ...interface{}) {
+	if err := l.writef(ctx, LevelFatal, format, args); err != nil {
 		fmt.Println(err)
 	}
 	if err := l.Flush(); err != nil {
@@ -150,7 +302,9 @@ func (l *Logger) Fatalf(format string, args ...interface{}) {
 }
 
 func (l *Logger) WriteBySkipCall(level Level, skipCall int, args ...interface{}) error {
-	if err := l.writer.WriteBySkipCall(level, skipCall, args...); err != nil {
+	buf := getArgsBuf()
+	defer buf.free()
+	if err := l.writer.WriteBySkipCall(level, skipCall, l.withExtra(buf, nil, args)...); err != nil {
 		return err
 	}
 
@@ -158,13 +312,83 @@ func (l *Logger) WriteBySkipCall(level Level, skipCall int, args ...interface{})
 }
 
 func (l *Logger) Write(level Level, args ...interface{}) error {
-	if err := l.writer.Write(level, args...); err != nil {
+	buf := getArgsBuf()
+	defer buf.free()
+	if err := l.writer.Write(level, l.withExtra(buf, nil, args)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WriteCtx 同Write,ctx用于提取trace
+func (l *Logger) WriteCtx(ctx context.Context, level Level, args ...interface{}) error {
+	buf := getArgsBuf()
+	defer buf.free()
+	if err := l.writer.Write(level, l.withExtra(buf, ctx, args)...); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// WriteBySkipCallCtx 同WriteBySkipCall,ctx用于提取trace
+func (l *Logger) WriteBySkipCallCtx(ctx context.Context, level Level, skipCall int, args ...interface{}) error {
+	buf := getArgsBuf()
+	defer buf.free()
+	if err := l.writer.WriteBySkipCall(level, skipCall, l.withExtra(buf, ctx, args)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writef 同WriteCtx,format作为第一个参数,和Write处于同一调用深度
+func (l *Logger) writef(ctx context.Context, level Level, format string, args []interface{}) error {
+	buf := getArgsBuf()
+	defer buf.free()
+	buf.args = append(buf.args, format)
+	if err := l.writer.Write(level, l.withExtra(buf, ctx, args)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// withExtra 把参数以及Logger绑定的字段和ctx写入复用的buf,参数已经携带Extra时合并
+func (l *Logger) withExtra(buf *argsBuf, ctx context.Context, args []interface{}) []interface{} {
+	args, argExtra := SplitExtra(args)
+	buf.args = append(buf.args, args...)
+	if len(l.fields) == 0 && ctx == nil && argExtra == nil {
+		return buf.args
+	}
+
+	buf.extra = Extra{Fields: l.fields, Ctx: ctx}
+	if argExtra != nil {
+		if len(l.fields) == 0 {
+			buf.extra.Fields = argExtra.Fields
+		} else if len(argExtra.Fields) > 0 {
+			buf.extra.Fields = make([]Field, 0, len(l.fields)+len(argExtra.Fields))
+			buf.extra.Fields = append(buf.extra.Fields, l.fields...)
+			buf.extra.Fields = append(buf.extra.Fields, argExtra.Fields...)
+		}
+		if argExtra.Ctx != nil {
+			buf.extra.Ctx = argExtra.Ctx
+		}
+		buf.extra.PC = argExtra.PC
+	}
+
+	return append(buf.args, &buf.extra)
+}
+
 func (l *Logger) Flush() error {
 	return l.writer.Flush()
 }
+
+// Close writer支持Close时关闭writer,子Logger共享writer,关闭后都不可再写入
+func (l *Logger) Close() error {
+	if closer, ok := l.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
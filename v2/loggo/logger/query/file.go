@@ -0,0 +1,149 @@
+package query
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
+	"github.com/995933447/log-go/v2/loggo/logger/writer"
+	"github.com/klauspost/compress/zstd"
+)
+
+// LogFile 目录中的一个日志分段,可能是已压缩的
+type LogFile struct {
+	Path      string
+	FirstTime time.Time // 第一条日志的时间,没有可解析的日志时为零值
+}
+
+var logFileSuffixes = []string{
+	writer.FileSuffix,
+	writer.FileSuffix + writer.GzipFileSuffix,
+	writer.FileSuffix + writer.ZstdFileSuffix,
+	writer.CompressedFileSuffix,
+}
+
+// IsLogFileName 是否为FileWriter生成的日志分段或者压缩文件
+func IsLogFileName(name string) bool {
+	for _, suffix := range logFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListLogFiles 列出dir中以prefix开头的日志分段,按第一条日志的时间排序.
+// 压缩文件的修改时间是压缩的时间,文件名中的序号也可能不连续,所以按内容排序.
+// 指向当前文件的软链接不会列出,避免重复读取
+func ListLogFiles(dir, prefix string) ([]LogFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []LogFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasPrefix(entry.Name(), prefix) || !IsLogFileName(entry.Name()) {
+			continue
+		}
+		file := LogFile{Path: filepath.Join(dir, entry.Name())}
+		if file.FirstTime, err = readFirstTime(file.Path); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].FirstTime.Equal(files[j].FirstTime) {
+			return files[i].FirstTime.Before(files[j].FirstTime)
+		}
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+func readFirstTime(path string) (time.Time, error) {
+	reader, err := OpenLogFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer reader.Close()
+
+	scanner := fmts.NewScanner(reader)
+	if scanner.Scan() {
+		return scanner.Record().Time, nil
+	}
+	return time.Time{}, scanner.Err()
+}
+
+// OpenLogFile 按后缀解压,zip只读取其中的第一个文件
+func OpenLogFile(path string) (io.ReadCloser, error) {
+	if strings.HasSuffix(path, writer.CompressedFileSuffix) {
+		return openZip(path)
+	}
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(path, writer.GzipFileSuffix):
+		gzipReader, err := gzip.NewReader(fp)
+		if err != nil {
+			_ = fp.Close()
+			return nil, err
+		}
+		return &decompressReader{Reader: gzipReader, closeFunc: func() error {
+			return errors.Join(gzipReader.Close(), fp.Close())
+		}}, nil
+	case strings.HasSuffix(path, writer.ZstdFileSuffix):
+		zstdReader, err := zstd.NewReader(fp)
+		if err != nil {
+			_ = fp.Close()
+			return nil, err
+		}
+		return &decompressReader{Reader: zstdReader, closeFunc: func() error {
+			zstdReader.Close()
+			return fp.Close()
+		}}, nil
+	}
+
+	return fp, nil
+}
+
+func openZip(path string) (io.ReadCloser, error) {
+	zipReader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(zipReader.File) == 0 {
+		_ = zipReader.Close()
+		return nil, errors.New("empty zip file " + path)
+	}
+	entry, err := zipReader.File[0].Open()
+	if err != nil {
+		_ = zipReader.Close()
+		return nil, err
+	}
+	return &decompressReader{Reader: entry, closeFunc: func() error {
+		return errors.Join(entry.Close(), zipReader.Close())
+	}}, nil
+}
+
+type decompressReader struct {
+	io.Reader
+	closeFunc func() error
+}
+
+func (r *decompressReader) Close() error {
+	return r.closeFunc()
+}
@@ -0,0 +1,102 @@
+package query
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
+)
+
+// ErrStop fn返回ErrStop时停止扫描,Scan返回nil
+var ErrStop = errors.New("stop scan")
+
+// Filter 日志过滤条件,零值的条件不生效
+type Filter struct {
+	MinLevel     logger.Level   // 最低级别
+	Since, Until time.Time      // 时间范围,包含Since不包含Until
+	Module       string         // 模块名,完全匹配
+	Trace        string         // trace id,完全匹配
+	Gid          int64          // 协程id
+	Caller       string         // 函数:文件:行号 中包含的子串
+	CallerRegexp *regexp.Regexp // 函数:文件:行号 匹配的正则
+}
+
+func (f *Filter) Match(rec *fmts.Record) bool {
+	if rec.Level < f.MinLevel {
+		return false
+	}
+	if !f.Since.IsZero() && rec.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !rec.Time.Before(f.Until) {
+		return false
+	}
+	if f.Module != "" && rec.Module != f.Module {
+		return false
+	}
+	if f.Trace != "" && rec.Trace != f.Trace {
+		return false
+	}
+	if f.Gid != 0 && rec.Gid != f.Gid {
+		return false
+	}
+	if f.Caller != "" || f.CallerRegexp != nil {
+		caller := FormatCaller(rec)
+		if f.Caller != "" && !strings.Contains(caller, f.Caller) {
+			return false
+		}
+		if f.CallerRegexp != nil && !f.CallerRegexp.MatchString(caller) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatCaller 与日志中一样的 函数:文件:行号
+func FormatCaller(rec *fmts.Record) string {
+	return rec.FuncName + ":" + rec.FileName + ":" + strconv.Itoa(rec.Line)
+}
+
+// Scan 按时间顺序读取dir中以prefix开头的日志分段,包括压缩文件,对符合filter的日志调用fn
+func Scan(dir, prefix string, filter *Filter, fn func(file string, rec *fmts.Record) error) error {
+	files, err := ListLogFiles(dir, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err = scanFile(file.Path, filter, fn); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanFile(path string, filter *Filter, fn func(file string, rec *fmts.Record) error) error {
+	reader, err := OpenLogFile(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	scanner := fmts.NewScanner(reader)
+	for scanner.Scan() {
+		rec := scanner.Record()
+		if filter != nil && !filter.Match(rec) {
+			continue
+		}
+		if err = fn(path, rec); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,98 @@
+package query
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
+	"github.com/klauspost/compress/zstd"
+)
+
+func writeTestLogFile(t *testing.T, path string, content string) {
+	var b bytes.Buffer
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		w := gzip.NewWriter(&b)
+		_, _ = w.Write([]byte(content))
+		_ = w.Close()
+	case strings.HasSuffix(path, ".zst"):
+		w, _ := zstd.NewWriter(&b)
+		_, _ = w.Write([]byte(content))
+		_ = w.Close()
+	case strings.HasSuffix(path, ".zip"):
+		w := zip.NewWriter(&b)
+		entry, _ := w.Create("app.txt")
+		_, _ = entry.Write([]byte(content))
+		_ = w.Close()
+	default:
+		b.WriteString(content)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	// 文件名的顺序与时间顺序不同,按第一条日志的时间读取
+	writeTestLogFile(t, dir+"/app.003.txt", ""+
+		"[01-02T13:00:00.0000] [order] [t2][3] WARN main.pay:pay.go:20 slow\n"+
+		"[01-02T13:00:01.0000] [order] [t1][3] ERR main.pay:pay.go:30 failed\n"+
+		"\tmain.pay\n"+
+		"\t\t/src/pay.go:30\n")
+	writeTestLogFile(t, dir+"/app.002.txt.zst", "[01-02T12:00:00.0000] [user] [t1][2] INFO main.login:login.go:10 login\n")
+	writeTestLogFile(t, dir+"/app.001.txt.gz", "[01-02T11:00:00.0000] [order] [t1][1] INFO main.order:order.go:5 created\n")
+	writeTestLogFile(t, dir+"/app.000_.txt.zip", "[01-02T10:00:00.0000] [order] [t0][1] DBG main.order:order.go:1 legacy\n")
+	// json和logfmt格式的分段
+	year := time.Now().Year()
+	writeTestLogFile(t, dir+"/app.004.txt.gz", `{"time":"`+time.Date(year, 1, 2, 14, 0, 0, 0, time.Local).Format(time.RFC3339Nano)+
+		`","module":"api","trace":"t1","gid":4,"level":"INFO","caller":"main.api:api.go:1","msg":"json"}`+"\n")
+	writeTestLogFile(t, dir+"/app.005.txt", "time="+time.Date(year, 1, 2, 15, 0, 0, 0, time.Local).Format(time.RFC3339Nano)+
+		" level=ERR module=api trace=t9 gid=5 caller=main.api:api.go:2 msg=logfmt\n")
+	writeTestLogFile(t, dir+"/other.001.txt", "[01-02T09:00:00.0000] [order] [t1][1] INFO main.other:other.go:1 other\n")
+	if err := os.Symlink("app.003.txt", dir+"/app.current.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(filter *Filter) []string {
+		var msgs []string
+		err := Scan(dir, "app.", filter, func(file string, rec *fmts.Record) error {
+			msgs = append(msgs, rec.Msg)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msgs
+	}
+	expect := func(got []string, expect ...string) {
+		t.Helper()
+		if len(got) != len(expect) {
+			t.Fatalf("expect %v, got %v", expect, got)
+		}
+		for i := range got {
+			if got[i] != expect[i] {
+				t.Fatalf("expect %v, got %v", expect, got)
+			}
+		}
+	}
+
+	expect(scan(nil), "legacy", "created", "login", "slow", "failed", "json", "logfmt")
+	expect(scan(&Filter{Trace: "t1"}), "created", "login", "failed", "json")
+	expect(scan(&Filter{MinLevel: logger.LevelWarn}), "slow", "failed", "logfmt")
+	expect(scan(&Filter{Module: "order", Gid: 3}), "slow", "failed")
+	expect(scan(&Filter{Caller: "pay.go:3"}), "failed")
+	expect(scan(&Filter{CallerRegexp: regexp.MustCompile(`^main\.(login|order):`)}), "legacy", "created", "login")
+
+	expect(scan(&Filter{
+		Since: time.Date(year, 1, 2, 11, 0, 0, 0, time.Local),
+		Until: time.Date(year, 1, 2, 13, 0, 0, 0, time.Local),
+	}), "created", "login")
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
+	"github.com/995933447/log-go/v2/loggo/logger/writer"
+)
+
+// legacyPeriodLayout 旧的按分钟命名方式中的时间
+const legacyPeriodLayout = "200601021504"
+
+// NodeDir 一个节点的日志目录,Node为空时取文件名中的节点id,文件名中没有时取目录名
+type NodeDir struct {
+	Node string
+	Dir  string
+}
+
+// TimelineEntry 时间线上的一条日志
+type TimelineEntry struct {
+	Node   string
+	File   string
+	Record *fmts.Record
+	Delta  time.Duration // 与上一条日志的时间差,第一条为0
+}
+
+// TraceTimeline 读取各节点目录中trace为traceId的日志,按时间合并成一条时间线.
+// 时间相同的日志保持各节点内的顺序以及dirs的顺序
+func TraceTimeline(dirs []NodeDir, prefix, traceId string) ([]TimelineEntry, error) {
+	var (
+		entries []TimelineEntry
+		filter  = &Filter{Trace: traceId}
+	)
+	for _, dir := range dirs {
+		err := Scan(dir.Dir, prefix, filter, func(file string, rec *fmts.Record) error {
+			node := dir.Node
+			if node == "" {
+				node = NodeFromFileName(prefix, file)
+			}
+			if node == "" {
+				node = filepath.Base(dir.Dir)
+			}
+			entries = append(entries, TimelineEntry{Node: node, File: file, Record: rec})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Record.Time.Before(entries[j].Record.Time)
+	})
+	for i := 1; i < len(entries); i++ {
+		entries[i].Delta = entries[i].Record.Time.Sub(entries[i-1].Record.Time)
+	}
+
+	return entries, nil
+}
+
+// NodeFromFileName 取出文件名中的节点id,支持 前缀.周期_节点id_分段序号.txt、前缀.节点id_分段序号.txt 以及旧的 前缀.年月日时分_节点id.txt,
+// 没有节点id时返回空字符串
+func NodeFromFileName(prefix, path string) string {
+	name := filepath.Base(path)
+	if prefix != "" {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, prefix), ".")
+	} else if dot := strings.IndexByte(name, '.'); dot >= 0 {
+		name = name[dot+1:]
+	}
+	if end := strings.Index(name, writer.FileSuffix); end >= 0 {
+		name = name[:end]
+	}
+
+	parts := strings.Split(name, "_")
+	switch {
+	case len(parts) == 3:
+		return parts[1]
+	case len(parts) == 2 && len(parts[0]) == len(legacyPeriodLayout):
+		// 没有节点id时第二部分是分段序号
+		return parts[1]
+	case len(parts) == 2 && !isPeriod(parts[0]):
+		// 只按大小切分时没有周期,是 前缀.节点id_分段序号.txt
+		return parts[0]
+	}
+	return ""
+}
+
+// minPeriodLen 周期至少包含年月,例如200601
+const minPeriodLen = 6
+
+// isPeriod 文件名中的周期是按时间格式化的数字
+func isPeriod(s string) bool {
+	if len(s) < minPeriodLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTraceTimeline(t *testing.T) {
+	nodeA, nodeB, nodeC := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTestLogFile(t, nodeA+"/default.2025010211_7_001.txt", ""+
+		"[01-02T11:00:00.0000] [gateway] [t1][1] INFO main.recv:recv.go:1 recv\n"+
+		"[01-02T11:00:00.5000] [gateway] [t2][1] INFO main.recv:recv.go:1 other trace\n"+
+		"[01-02T11:00:03.0000] [gateway] [t1][1] INFO main.reply:reply.go:1 reply\n")
+	writeTestLogFile(t, nodeB+"/default.202501021100_8.txt.gz", ""+
+		"[01-02T11:00:01.0000] [order] [t1][5] INFO main.order:order.go:1 order\n"+
+		"[01-02T11:00:02.0000] [order] [t1][5] ERR main.pay:pay.go:1 pay failed\n"+
+		"\tmain.pay\n"+
+		"\t\t/src/pay.go:1\n")
+	writeTestLogFile(t, nodeC+"/default.001.txt", "[01-02T11:00:02.0000] [stock] [t1][9] INFO main.stock:stock.go:1 stock\n")
+
+	entries, err := TraceTimeline([]NodeDir{{Dir: nodeA}, {Dir: nodeB}, {Node: "stock-1", Dir: nodeC}}, "default.", "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []struct {
+		node, module, msg string
+		delta             time.Duration
+	}{
+		{"7", "gateway", "recv", 0},
+		{"8", "order", "order", time.Second},
+		{"8", "order", "pay failed", time.Second},
+		{"stock-1", "stock", "stock", 0},
+		{"7", "gateway", "reply", time.Second},
+	}
+	if len(entries) != len(expect) {
+		t.Fatalf("expect %d entries, got %d", len(expect), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Node != expect[i].node || entry.Record.Module != expect[i].module || entry.Record.Msg != expect[i].msg || entry.Delta != expect[i].delta {
+			t.Fatalf("unexpected entry %d: node:%s module:%s msg:%s delta:%s", i, entry.Node, entry.Record.Module, entry.Record.Msg, entry.Delta)
+		}
+	}
+	if entries[2].Record.Stack != "main.pay\n\t/src/pay.go:1\n" {
+		t.Fatalf("stack should be kept, got %q", entries[2].Record.Stack)
+	}
+
+	// 文件名中没有节点id时使用目录名
+	if err = os.Rename(nodeA+"/default.2025010211_7_001.txt", nodeA+"/default.2025010211_001.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err = TraceTimeline([]NodeDir{{Dir: nodeA}}, "default.", "t1"); err != nil || len(entries) != 2 {
+		t.Fatalf("unexpected entries %+v %v", entries, err)
+	}
+	if entries[0].Node != filepath.Base(nodeA) {
+		t.Fatalf("expect dir name as node, got %s", entries[0].Node)
+	}
+}
+
+func TestNodeFromFileName(t *testing.T) {
+	cases := map[string]string{
+		"/logs/default.2025010215_7_001.txt":    "7",
+		"/logs/default.202501021504_12.txt.gz":  "12",
+		"/logs/default.2025010215_001.txt.zst":  "",
+		"/logs/default.001.txt":                 "",
+		"/logs/default.202501021504_12.txt.zip": "12",
+		"/logs/default.3_001.txt":               "3",
+		"/logs/default.node-a_002.txt.gz":       "node-a",
+	}
+	for path, node := range cases {
+		if got := NodeFromFileName("default", path); got != node {
+			t.Fatalf("%s: expect %q, got %q", path, node, got)
+		}
+		if got := NodeFromFileName("", path); got != node {
+			t.Fatalf("%s without prefix: expect %q, got %q", path, node, got)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLevelImportant slog中对应LevelImportant的级别,介于Info和Warn之间
+const SlogLevelImportant = slog.Level(2)
+
+// TransSlogLevel slog级别映射到Level
+func TransSlogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return LevelError
+	case level >= slog.LevelWarn:
+		return LevelWarn
+	case level >= SlogLevelImportant:
+		return LevelImportant
+	case level >= slog.LevelInfo:
+		return LevelInfo
+	}
+	return LevelDebug
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// SlogHandler 把slog记录写入Writer,attrs和groups作为结构化字段,调用方取Record.PC
+type SlogHandler struct {
+	writer      Writer
+	fields      []Field
+	groupPrefix string
+}
+
+func NewSlogHandler(writer Writer) *SlogHandler {
+	return &SlogHandler{
+		writer: writer,
+	}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.writer.IsLoggable(TransSlogLevel(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make([]Field, 0, len(h.fields)+record.NumAttrs())
+	fields = append(fields, h.fields...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.groupPrefix, attr)
+		return true
+	})
+
+	return h.writer.Write(TransSlogLevel(record.Level), record.Message, &Extra{
+		Fields: fields,
+		Ctx:    ctx,
+		PC:     record.PC,
+	})
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	child := *h
+	child.fields = make([]Field, 0, len(h.fields)+len(attrs))
+	child.fields = append(child.fields, h.fields...)
+	for _, attr := range attrs {
+		child.fields = appendSlogAttr(child.fields, h.groupPrefix, attr)
+	}
+	return &child
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.groupPrefix = h.groupPrefix + name + "."
+	return &child
+}
+
+func appendSlogAttr(fields []Field, groupPrefix string, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	key := groupPrefix + attr.Key
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		groupAttrs := attr.Value.Group()
+		if len(groupAttrs) == 0 {
+			return fields
+		}
+		// 匿名group的属性直接内联
+		if attr.Key != "" {
+			groupPrefix = key + "."
+		}
+		for _, groupAttr := range groupAttrs {
+			fields = appendSlogAttr(fields, groupPrefix, groupAttr)
+		}
+		return fields
+	case slog.KindString:
+		return append(fields, String(key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(fields, Int64(key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, Uint64(key, attr.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, Float64(key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, Bool(key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, Duration(key, attr.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, Time(key, attr.Value.Time()))
+	}
+	return append(fields, Any(key, attr.Value.Any()))
+}
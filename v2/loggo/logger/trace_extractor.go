@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	simpletracectx "github.com/995933447/simpletrace/context"
+)
+
+// TraceExtractor 从ctx中提取trace id,ok为false时formatter回退到协程本地trace
+type TraceExtractor interface {
+	ExtractTrace(ctx context.Context) (string, bool)
+}
+
+type TraceExtractorFunc func(ctx context.Context) (string, bool)
+
+func (f TraceExtractorFunc) ExtractTrace(ctx context.Context) (string, bool) {
+	return f(ctx)
+}
+
+// ChainTraceExtractor 依次尝试多个extractor,返回第一个提取成功的结果
+func ChainTraceExtractor(extractors ...TraceExtractor) TraceExtractor {
+	return TraceExtractorFunc(func(ctx context.Context) (string, bool) {
+		for _, extractor := range extractors {
+			if trace, ok := extractor.ExtractTrace(ctx); ok {
+				return trace, true
+			}
+		}
+		return "", false
+	})
+}
+
+// SimpleTraceExtractor 提取simpletrace context的trace id
+var SimpleTraceExtractor TraceExtractor = TraceExtractorFunc(func(ctx context.Context) (string, bool) {
+	traceCtx, ok := ctx.(*simpletracectx.Context)
+	if !ok || traceCtx == nil || traceCtx.GetTraceId() == "" {
+		return "", false
+	}
+	return traceCtx.GetTraceId(), true
+})
+
+// TraceparentExtractor 提取ContextWithTraceparent存入的W3C traceparent中的trace id
+var TraceparentExtractor TraceExtractor = TraceExtractorFunc(func(ctx context.Context) (string, bool) {
+	traceparent, ok := TraceparentFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return ParseTraceparent(traceparent)
+})
+
+type traceparentCtxKey struct{}
+
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentCtxKey{}, traceparent)
+}
+
+func TraceparentFromContext(ctx context.Context) (string, bool) {
+	traceparent, ok := ctx.Value(traceparentCtxKey{}).(string)
+	return traceparent, ok && traceparent != ""
+}
+
+// ParseTraceparent 解析version-traceid-parentid-flags格式,返回trace id
+func ParseTraceparent(traceparent string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return "", false
+	}
+	version, traceId, parentId, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" || !isLowerHex(version) {
+		return "", false
+	}
+	if version == "00" && len(parts) != 4 {
+		return "", false
+	}
+	if len(traceId) != 32 || !isLowerHex(traceId) || traceId == strings.Repeat("0", 32) {
+		return "", false
+	}
+	if len(parentId) != 16 || !isLowerHex(parentId) || parentId == strings.Repeat("0", 16) {
+		return "", false
+	}
+	if len(flags) != 2 || !isLowerHex(flags) {
+		return "", false
+	}
+	return traceId, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+var traceExtractor atomic.Value
+
+func init() {
+	SetTraceExtractor(ChainTraceExtractor(SimpleTraceExtractor, TraceparentExtractor))
+}
+
+// SetTraceExtractor 替换全局TraceExtractor,默认支持simpletrace和traceparent
+func SetTraceExtractor(extractor TraceExtractor) {
+	traceExtractor.Store(&extractor)
+}
+
+func GetTraceExtractor() TraceExtractor {
+	return *traceExtractor.Load().(*TraceExtractor)
+}
+
+// ExtractTrace 使用全局TraceExtractor从ctx中提取trace id
+func ExtractTrace(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	return GetTraceExtractor().ExtractTrace(ctx)
+}
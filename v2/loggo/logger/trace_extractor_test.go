@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceId  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentId = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name        string
+		traceparent string
+		ok          bool
+	}{
+		{"valid", "00-" + traceId + "-" + parentId + "-01", true},
+		{"valid with spaces", " 00-" + traceId + "-" + parentId + "-00 ", true},
+		{"future version with extra part", "01-" + traceId + "-" + parentId + "-01-what", true},
+		{"invalid version ff", "ff-" + traceId + "-" + parentId + "-01", false},
+		{"version 00 with extra part", "00-" + traceId + "-" + parentId + "-01-what", false},
+		{"short version", "0-" + traceId + "-" + parentId + "-01", false},
+		{"missing part", "00-" + traceId + "-" + parentId, false},
+		{"short trace id", "00-" + traceId[1:] + "-" + parentId + "-01", false},
+		{"long trace id", "00-" + traceId + "0-" + parentId + "-01", false},
+		{"short parent id", "00-" + traceId + "-" + parentId[1:] + "-01", false},
+		{"long flags", "00-" + traceId + "-" + parentId + "-011", false},
+		{"zero trace id", "00-00000000000000000000000000000000-" + parentId + "-01", false},
+		{"zero parent id", "00-" + traceId + "-0000000000000000-01", false},
+		{"uppercase trace id", "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + parentId + "-01", false},
+		{"uppercase parent id", "00-" + traceId + "-00F067AA0BA902B7-01", false},
+		{"uppercase version", "0A-" + traceId + "-" + parentId + "-01", false},
+		{"not hex", "00-" + traceId[:31] + "g-" + parentId + "-01", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTraceparent(tt.traceparent)
+			if ok != tt.ok {
+				t.Fatalf("ParseTraceparent(%q) ok = %v, want %v", tt.traceparent, ok, tt.ok)
+			}
+			if ok && got != traceId {
+				t.Fatalf("ParseTraceparent(%q) = %s, want %s", tt.traceparent, got, traceId)
+			}
+		})
+	}
+}
+
+func TestTraceparentExtractor(t *testing.T) {
+	ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if trace, ok := ExtractTrace(ctx); !ok || trace != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("unexpected trace: %s", trace)
+	}
+	if _, ok := ExtractTrace(ContextWithTraceparent(context.Background(), "00-bad")); ok {
+		t.Fatal("malformed traceparent should not be extracted")
+	}
+	if _, ok := ExtractTrace(context.Background()); ok {
+		t.Fatal("ctx without traceparent should not be extracted")
+	}
+}
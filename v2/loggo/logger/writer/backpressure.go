@@ -0,0 +1,216 @@
+package writer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// BufFullPolicy 缓冲区写满时的处理策略
+type BufFullPolicy int
+
+const (
+	BufFullPolicyDropNewest BufFullPolicy = iota // 丢弃当前写入的日志
+	BufFullPolicyDropOldest                      // 丢弃缓冲区中最早的日志腾出位置
+	BufFullPolicyBlock                           // 阻塞等待,超过BlockTimeout后丢弃
+	BufFullPolicySpill                           // 写入本地溢出文件,缓冲区排空后回放到日志文件
+)
+
+const (
+	defaultBlockTimeout     = time.Second
+	spillFileSuffix         = ".spill"
+	spillReplayChunkBytes   = 64 * 1024
+	fullBufChTipIntervalSec = 5
+)
+
+// hdlBufFull 缓冲区写满时按配置的策略处理,丢弃的日志计入droppedLines
+func (w *FileWriter) hdlBufFull(logContent []byte) {
+	switch w.cfg.BufFullPolicy {
+	case BufFullPolicyDropOldest:
+		for w.queue.canFit(len(logContent)) && w.queue.dropOldest() {
+			w.droppedLines.Add(1)
+			w.metrics.droppedLines.Add(1)
+			if w.queue.tryPush(logContent) {
+				w.metrics.accept(len(logContent), w.queue.usedBytes())
+				return
+			}
+		}
+	case BufFullPolicyBlock:
+		if !w.queue.canFit(len(logContent)) {
+			break
+		}
+		timeout := w.cfg.BlockTimeout
+		if timeout <= 0 {
+			timeout = defaultBlockTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		w.queue.freedWaiters.Add(1)
+		defer w.queue.freedWaiters.Add(-1)
+		for isTimeout := false; !isTimeout; {
+			freedCh := w.queue.waitFreed()
+			if w.queue.tryPush(logContent) {
+				w.metrics.accept(len(logContent), w.queue.usedBytes())
+				return
+			}
+			select {
+			case <-freedCh:
+			case <-timer.C:
+				isTimeout = true
+			}
+		}
+	case BufFullPolicySpill:
+		err := w.spill(logContent)
+		if err == nil {
+			w.metrics.accept(len(logContent), w.queue.usedBytes())
+			return
+		}
+		w.hdlLogErr(err)
+	}
+
+	w.droppedLines.Add(1)
+	w.metrics.droppedLines.Add(1)
+	if time.Now().Unix()-w.lastFullBufChTipAt.Load() > fullBufChTipIntervalSec {
+		fmt.Println("log buffer is full, content:", string(logContent))
+		w.lastFullBufChTipAt.Store(time.Now().Unix())
+	}
+}
+
+func (w *FileWriter) getSpillFileName() string {
+	if w.cfg.SpillFile != "" {
+		return w.cfg.SpillFile
+	}
+	return w.cfg.BaseDir + "/" + w.getFilePrefix() + "overflow" + spillFileSuffix
+}
+
+// openSpillFile 调用方需持有spillMu
+func (w *FileWriter) openSpillFile() error {
+	if w.spillFp != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(w.cfg.BaseDir, 0755); err != nil {
+		return err
+	}
+
+	var err error
+	if w.spillFp, err = os.OpenFile(w.getSpillFileName(), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644); err != nil {
+		return err
+	}
+
+	// 上次进程退出时残留的溢出日志也需要回放
+	fileInfo, err := w.spillFp.Stat()
+	if err != nil {
+		return err
+	}
+	if fileInfo.Size() > 0 {
+		w.spilledLines.Add(1)
+	}
+
+	return nil
+}
+
+func (w *FileWriter) spill(logContent []byte) error {
+	w.spillMu.Lock()
+	defer w.spillMu.Unlock()
+
+	if err := w.openSpillFile(); err != nil {
+		return err
+	}
+
+	if _, err := w.spillFp.Write(logContent); err != nil {
+		return err
+	}
+	w.spilledLines.Add(1)
+
+	return nil
+}
+
+// replaySpill 把溢出文件中的日志写入日志文件并清空溢出文件,由Loop在缓冲区排空时调用.
+// 回放的日志会排在溢出期间之后进入缓冲区的日志后面
+func (w *FileWriter) replaySpill() error {
+	if w.cfg.BufFullPolicy != BufFullPolicySpill {
+		return nil
+	}
+
+	w.spillMu.Lock()
+	defer w.spillMu.Unlock()
+
+	if w.spillFp == nil {
+		// 启动时检查残留的溢出文件
+		if _, err := os.Stat(w.getSpillFileName()); err != nil {
+			return nil
+		}
+		if err := w.openSpillFile(); err != nil {
+			return err
+		}
+	}
+
+	if w.spilledLines.Load() == 0 {
+		return nil
+	}
+
+	if _, err := w.spillFp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReaderSize(w.spillFp, spillReplayChunkBytes)
+	batch := make([]byte, 0, spillReplayChunkBytes)
+	for {
+		line, err := reader.ReadSlice('\n')
+		batch = append(batch, line...)
+		if err == bufio.ErrBufferFull {
+			// 超长的行读完整后再写
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		// 只在行尾处分批写入,中途切换文件时不会把一行拆到两个文件中
+		if len(batch) > 0 && (len(batch) >= spillReplayChunkBytes || err == io.EOF) {
+			if err := w.writeBuf(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if err := w.spillFp.Truncate(0); err != nil {
+		return err
+	}
+	w.spilledLines.Store(0)
+
+	return nil
+}
+
+// closeSpillFile 回放完成后关闭并删除溢出文件
+func (w *FileWriter) closeSpillFile() error {
+	w.spillMu.Lock()
+	defer w.spillMu.Unlock()
+
+	if w.spillFp == nil {
+		return nil
+	}
+	if err := w.spillFp.Close(); err != nil {
+		return err
+	}
+	w.spillFp = nil
+	if w.spilledLines.Load() > 0 {
+		return nil
+	}
+	return os.Remove(w.getSpillFileName())
+}
+
+// takeDroppedReport 生成自上次报告以来丢弃日志数量的统计行
+func (w *FileWriter) takeDroppedReport() []byte {
+	dropped := w.droppedLines.Swap(0)
+	if dropped == 0 {
+		return nil
+	}
+
+	return w.appendNotice(nil, "dropped %d lines because log buffer is full", dropped)
+}
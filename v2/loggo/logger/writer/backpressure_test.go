@@ -0,0 +1,177 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
+)
+
+func TestBufFullPolicy(t *testing.T) {
+	newWriter := func(policy BufFullPolicy) *FileWriter {
+		fileWriter := newTestFileWriter(t, &FileWriterConf{
+			BufSizeBytes:  512,
+			BufFullPolicy: policy,
+		})
+		for i := 0; i < 5; i++ {
+			if err := fileWriter.Write(logger.LevelInfo, "line %d %s", i, linePadding); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return fileWriter
+	}
+
+	dropOldestWriter := newWriter(BufFullPolicyDropOldest)
+	if dropped := dropOldestWriter.droppedLines.Load(); dropped != 3 {
+		t.Fatalf("expect 3 dropped lines, got %d", dropped)
+	}
+	if line := popLine(dropOldestWriter); !strings.Contains(line, " line 3 ") {
+		t.Fatalf("oldest lines should be dropped, got %s", line)
+	}
+
+	// json格式下统计行也是一条完整的json日志
+	dropOldestWriter.SetFormatter(fmts.NewTraceFormatter("", 4, fmts.FormatJSON, true, false, dropOldestWriter.cfg.LogCfgLoader))
+	dropOldestWriter.droppedLines.Store(3)
+	if report := dropOldestWriter.takeDroppedReport(); !json.Valid(report) || !strings.Contains(string(report), `"level":"WARN"`) ||
+		!strings.Contains(string(report), "dropped 3 lines because log buffer is full") {
+		t.Fatalf("unexpected report %s", report)
+	}
+
+	spillWriter := newWriter(BufFullPolicySpill)
+	if spilled := spillWriter.spilledLines.Load(); spilled != 3 {
+		t.Fatalf("expect 3 spilled lines, got %d", spilled)
+	}
+	if err := spillWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(spillWriter.cfg.BaseDir + "/" + spillWriter.GetCurFileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if !strings.Contains(string(content), fmt.Sprintf(" line %d ", i)) {
+			t.Fatalf("line %d lost: %s", i, content)
+		}
+	}
+	if _, err = os.Stat(spillWriter.getSpillFileName()); !os.IsNotExist(err) {
+		t.Fatalf("spill file should be removed, err: %v", err)
+	}
+}
+
+func TestBufFullPolicyDropNewest(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		BufSizeBytes:  512,
+		BufFullPolicy: BufFullPolicyDropNewest,
+	})
+	for i := 0; i < 5; i++ {
+		if err := fileWriter.Write(logger.LevelInfo, "line %d %s", i, linePadding); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dropped := fileWriter.droppedLines.Load(); dropped != 3 {
+		t.Fatalf("expect 3 dropped lines, got %d", dropped)
+	}
+	for i := 0; i < 2; i++ {
+		if line := popLine(fileWriter); !strings.Contains(line, fmt.Sprintf(" line %d ", i)) {
+			t.Fatalf("newest lines should be dropped, got %s", line)
+		}
+	}
+}
+
+func TestBufFullPolicyBlock(t *testing.T) {
+	newWriter := func(timeout time.Duration) *FileWriter {
+		fileWriter := newTestFileWriter(t, &FileWriterConf{
+			BufSizeBytes:  512,
+			BufFullPolicy: BufFullPolicyBlock,
+			BlockTimeout:  timeout,
+		})
+		for i := 0; i < 2; i++ {
+			if err := fileWriter.Write(logger.LevelInfo, "line %d %s", i, linePadding); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return fileWriter
+	}
+
+	// 没有消费者时等待超时后丢弃
+	timeoutWriter := newWriter(time.Millisecond * 50)
+	startAt := time.Now()
+	if err := timeoutWriter.Write(logger.LevelInfo, "line 2 %s", linePadding); err != nil {
+		t.Fatal(err)
+	}
+	if cost := time.Since(startAt); cost < time.Millisecond*50 || timeoutWriter.droppedLines.Load() != 1 {
+		t.Fatalf("expect dropped after timeout, cost %s dropped %d", cost, timeoutWriter.droppedLines.Load())
+	}
+
+	// 消费者释放空间后唤醒阻塞的生产者
+	blockedWriter := newWriter(time.Minute)
+	writeDone := make(chan error)
+	go func() {
+		writeDone <- blockedWriter.Write(logger.LevelInfo, "line 2 %s", linePadding)
+	}()
+	for blockedWriter.queue.freedWaiters.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	popLine(blockedWriter)
+	select {
+	case err := <-writeDone:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("blocked write should be woken up after space is freed")
+	}
+	if blockedWriter.droppedLines.Load() != 0 || blockedWriter.queue.len() != 2 {
+		t.Fatalf("expect no dropped lines, got %d, queue len %d", blockedWriter.droppedLines.Load(), blockedWriter.queue.len())
+	}
+	popLine(blockedWriter)
+	if line := popLine(blockedWriter); !strings.Contains(line, " line 2 ") {
+		t.Fatalf("unexpected line %s", line)
+	}
+}
+
+func TestReplaySpillByLine(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		FilePrefix:     "app",
+		BufFullPolicy:  BufFullPolicySpill,
+		RotationPolicy: NewSizeRotation(spillReplayChunkBytes / 2),
+	})
+	// 溢出的内容超过一次回放的大小,每个分段都要以完整的行结尾
+	var spilled int
+	for ; spilled*len(linePadding) < spillReplayChunkBytes*2; spilled++ {
+		if err := fileWriter.spill([]byte(fmt.Sprintf("line %d %s\n", spilled, linePadding))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fileWriter.replaySpill(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var replayed int
+	for segment := 1; ; segment++ {
+		content, err := os.ReadFile(fmt.Sprintf("%s/app.%03d.txt", fileWriter.cfg.BaseDir, segment))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			if line != fmt.Sprintf("line %d %s", replayed, linePadding) {
+				t.Fatalf("segment %d has broken line %q", segment, line)
+			}
+			replayed++
+		}
+	}
+	if replayed != spilled {
+		t.Fatalf("expect %d lines replayed, got %d", spilled, replayed)
+	}
+}
@@ -0,0 +1,34 @@
+package writer
+
+import "sync"
+
+const (
+	// 超过该大小的缓冲不放回池中,避免偶发的大日志长期占用内存
+	maxPooledBufferBytes = 64 * 1024
+	batchWriteBytes      = 16 * 1024
+	defaultBufSizeBytes  = 8 * 1024 * 1024
+	estimatedLineBytes   = 256
+)
+
+// buffer 池化的日志行缓冲,拷贝进缓冲区后放回池中
+type buffer struct {
+	b []byte
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &buffer{b: make([]byte, 0, 512)}
+	},
+}
+
+func getBuffer() *buffer {
+	return bufferPool.Get().(*buffer)
+}
+
+func (buf *buffer) free() {
+	if cap(buf.b) > maxPooledBufferBytes {
+		return
+	}
+	buf.b = buf.b[:0]
+	bufferPool.Put(buf)
+}
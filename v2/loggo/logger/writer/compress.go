@@ -0,0 +1,255 @@
+package writer
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressFormatGzip = "gzip"
+	CompressFormatZstd = "zstd"
+
+	GzipFileSuffix = ".gz"
+	ZstdFileSuffix = ".zst"
+
+	compressTmpFileSuffix = ".tmp"
+	compressQueueLen      = 64
+)
+
+// compressedFileSuffixes 压缩后日志文件的后缀,包括旧版本生成的zip
+var compressedFileSuffixes = []string{FileSuffix + GzipFileSuffix, FileSuffix + ZstdFileSuffix, CompressedFileSuffix}
+
+// ErrUnknownCompressFormat CompressFormat不是gzip或zstd
+var ErrUnknownCompressFormat = errors.New("unknown compress format")
+
+// getCompressFormat 没有配置压缩时返回空字符串
+func getCompressFormat(cfg *logger.FileLogConf) string {
+	format := strings.ToLower(cfg.CompressFormat)
+	if format == "" && cfg.CompressFrequentHours > 0 {
+		format = CompressFormatGzip
+	}
+	return format
+}
+
+func getCompressedFileSuffix(format string) (string, error) {
+	switch format {
+	case CompressFormatGzip:
+		return GzipFileSuffix, nil
+	case CompressFormatZstd:
+		return ZstdFileSuffix, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownCompressFormat, format)
+}
+
+// isLogFile 是否为当前前缀的日志文件,包括已压缩的,不包括指向当前文件的软链接
+func (w *FileWriter) isLogFile(path string) bool {
+	fileName := filepath.Base(path)
+	if !strings.HasPrefix(fileName, w.getFilePrefix()) || fileName == w.getCurrentLinkName() {
+		return false
+	}
+	if strings.HasSuffix(fileName, FileSuffix) {
+		return true
+	}
+	for _, suffix := range compressedFileSuffixes {
+		if strings.HasSuffix(fileName, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// logFileExists 日志文件或者它的压缩文件存在
+func (w *FileWriter) logFileExists(fileName string) bool {
+	if _, err := os.Stat(w.cfg.BaseDir + "/" + fileName); !os.IsNotExist(err) {
+		return true
+	}
+	for _, suffix := range compressedFileSuffixes {
+		if _, err := os.Stat(w.cfg.BaseDir + "/" + strings.TrimSuffix(fileName, FileSuffix) + suffix); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldCompress 只压缩已经切换掉的分段,当前正在写的文件不压缩
+func (w *FileWriter) shouldCompress(path string, info os.FileInfo, logCfg *logger.FileLogConf) bool {
+	if getCompressFormat(logCfg) == "" {
+		return false
+	}
+	if !w.isLogFile(path) || !strings.HasSuffix(path, FileSuffix) {
+		return false
+	}
+	if filepath.Base(path) == w.GetCurFileName() {
+		return false
+	}
+	if logCfg.CompressFrequentHours > 0 && info.ModTime().Unix() >= time.Now().Unix()-3600*int64(logCfg.CompressFrequentHours) {
+		return false
+	}
+	if logCfg.CompressAfterReachBytes > 0 && info.Size() < logCfg.CompressAfterReachBytes {
+		return false
+	}
+	return true
+}
+
+// enqueueCompress 交给压缩协程处理,队列满时跳过,下次清理过期文件时会再次扫描到
+func (w *FileWriter) enqueueCompress(path string) {
+	select {
+	case w.compressCh <- path:
+	default:
+	}
+}
+
+// compressLoop 逐个压缩队列中的文件,Close时处理完当前文件后退出
+func (w *FileWriter) compressLoop() {
+	defer close(w.compressDoneCh)
+	for {
+		select {
+		case path := <-w.compressCh:
+			if err := w.compressFile(path); err != nil {
+				w.hdlLogErr(err)
+			}
+		case <-w.compressStopCh:
+			return
+		}
+	}
+}
+
+// compressFile 压缩到临时文件,校验解压后的内容与源文件一致后rename为正式文件,再删除源文件
+func (w *FileWriter) compressFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 已经被压缩或者删除
+			return nil
+		}
+		return err
+	}
+
+	logCfg := w.cfg.LogCfgLoader.GetConf().File
+	if !w.shouldCompress(path, info, &logCfg) {
+		return nil
+	}
+
+	format := getCompressFormat(&logCfg)
+	suffix, err := getCompressedFileSuffix(format)
+	if err != nil {
+		return err
+	}
+
+	dstPath := path + suffix
+	tmpPath := dstPath + compressTmpFileSuffix
+	size, sum, err := compressToFile(path, tmpPath, format)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err = verifyCompressedFile(tmpPath, format, size, sum); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("verify compressed file %s failed: %w", tmpPath, err)
+	}
+
+	// 压缩期间源文件又被写入,例如旧的按分钟命名的方式重新打开了同一个文件
+	if info, err = os.Stat(path); err != nil || info.Size() != size || filepath.Base(path) == w.GetCurFileName() {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err = os.Rename(tmpPath, dstPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// compressToFile 返回源文件的字节数和crc32
+func compressToFile(srcPath, dstPath, format string) (int64, uint32, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer dst.Close()
+
+	var enc io.WriteCloser
+	switch format {
+	case CompressFormatGzip:
+		enc = gzip.NewWriter(dst)
+	case CompressFormatZstd:
+		if enc, err = zstd.NewWriter(dst); err != nil {
+			return 0, 0, err
+		}
+	default:
+		return 0, 0, fmt.Errorf("%w: %s", ErrUnknownCompressFormat, format)
+	}
+
+	hash := crc32.NewIEEE()
+	size, err := io.Copy(enc, io.TeeReader(src, hash))
+	if err != nil {
+		_ = enc.Close()
+		return 0, 0, err
+	}
+	if err = enc.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err = dst.Sync(); err != nil {
+		return 0, 0, err
+	}
+
+	return size, hash.Sum32(), dst.Close()
+}
+
+func verifyCompressedFile(path, format string, expectSize int64, expectSum uint32) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	var dec io.Reader
+	switch format {
+	case CompressFormatGzip:
+		gzipReader, err := gzip.NewReader(fp)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		dec = gzipReader
+	case CompressFormatZstd:
+		zstdReader, err := zstd.NewReader(fp)
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+		dec = zstdReader
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownCompressFormat, format)
+	}
+
+	hash := crc32.NewIEEE()
+	size, err := io.Copy(hash, dec)
+	if err != nil {
+		return err
+	}
+	if size != expectSize || hash.Sum32() != expectSum {
+		return fmt.Errorf("content mismatch, size:%d expect:%d", size, expectSize)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressFile(t *testing.T) {
+	for _, format := range []string{CompressFormatGzip, CompressFormatZstd} {
+		t.Run(format, func(t *testing.T) {
+			baseDir := t.TempDir()
+			fileWriter := newTestFileWriter(t, &FileWriterConf{
+				BaseDir:    baseDir,
+				FilePrefix: "app",
+				LogCfgLoader: newTestConfLoader(t, &logger.LogConf{
+					File: logger.FileLogConf{Level: "INFO", LogInfoBeforeFileSizeBytes: -1, CompressFormat: format},
+				}),
+				RotationPolicy: NewSizeRotation(300),
+			})
+			var err error
+
+			// 写出app.001.txt,app.002.txt两个已切换的分段,当前文件为app.003.txt
+			for i := 0; i < 5; i++ {
+				if err = fileWriter.Write(logger.LevelInfo, "line %d %s", i, linePadding); err != nil {
+					t.Fatal(err)
+				}
+				if _, err = fileWriter.writeQueued(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			originals := make(map[string][]byte)
+			for _, name := range []string{"app.001.txt", "app.002.txt", "app.003.txt"} {
+				if originals[name], err = os.ReadFile(baseDir + "/" + name); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// 切换时和扫描时都会把已切换的分段放入队列,重复的文件已不存在时跳过
+			fileWriter.hdlExpiredFiles()
+			queued := make(map[string]bool)
+			for len(fileWriter.compressCh) > 0 {
+				path := <-fileWriter.compressCh
+				queued[path] = true
+				if err = fileWriter.compressFile(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(queued) != 2 || !queued[baseDir+"/app.001.txt"] || !queued[baseDir+"/app.002.txt"] {
+				t.Fatalf("expect only rotated segments queued, got %v", queued)
+			}
+			if err = fileWriter.compressFile(baseDir + "/app.003.txt"); err != nil {
+				t.Fatal(err)
+			}
+
+			suffix, _ := getCompressedFileSuffix(format)
+			for _, name := range []string{"app.001.txt", "app.002.txt"} {
+				if _, err = os.Stat(baseDir + "/" + name); !os.IsNotExist(err) {
+					t.Fatalf("source %s should be removed", name)
+				}
+				if content := readCompressedFile(t, baseDir+"/"+name+suffix, format); !bytes.Equal(content, originals[name]) {
+					t.Fatalf("%s content mismatch", name)
+				}
+			}
+			if _, err = os.Stat(baseDir + "/app.003.txt" + suffix); !os.IsNotExist(err) {
+				t.Fatal("current file should not be compressed")
+			}
+			if err = fileWriter.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func readCompressedFile(t *testing.T, path, format string) []byte {
+	fp, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	var reader io.Reader
+	if format == CompressFormatGzip {
+		gzipReader, err := gzip.NewReader(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = gzipReader
+	} else {
+		zstdReader, err := zstd.NewReader(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestUnknownCompressFormat(t *testing.T) {
+	_, err := NewFileWriter(&FileWriterConf{
+		BaseDir:  t.TempDir(),
+		SkipCall: 4,
+		LogCfgLoader: newTestConfLoader(t, &logger.LogConf{
+			File: logger.FileLogConf{Level: "INFO", CompressFormat: "zip"},
+		}),
+	})
+	if !errors.Is(err, ErrUnknownCompressFormat) {
+		t.Fatalf("expect ErrUnknownCompressFormat, got %v", err)
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
-	"github.com/995933447/gofiler"
 	"github.com/995933447/log-go/v2/loggo/logger"
 	"github.com/995933447/log-go/v2/loggo/logger/fmts"
 )
@@ -18,6 +21,9 @@ import (
 const (
 	FileSuffix           = ".txt"
 	CompressedFileSuffix = ".zip"
+
+	defaultCurrentLinkName      = "current" + FileSuffix
+	defaultCheckFileIntervalSec = 1
 )
 
 var levelToStdoutColorMap = map[logger.Level]logger.Color{
@@ -30,8 +36,10 @@ var levelToStdoutColorMap = map[logger.Level]logger.Color{
 	logger.LevelFatal:     logger.ColorPurple,
 }
 
+// CheckTimeToOpenNewFileFunc Deprecated: 使用RotationPolicy
 type CheckTimeToOpenNewFileFunc func(writer *FileWriter, lastOpenFileTime *time.Time, isNeverOpenFile bool) (string, bool)
 
+// OpenNewFileByByDateHour Deprecated: 按分钟命名,同一分钟内按大小切换会写回同一个文件,使用RotationPolicy
 var OpenNewFileByByDateHour CheckTimeToOpenNewFileFunc = func(writer *FileWriter, lastOpenFileTime *time.Time, isNeverOpenFile bool) (string, bool) {
 	fileName := writer.getFilePrefix() + time.Now().Format("200601021504") + "_" + FileSuffix
 
@@ -60,6 +68,9 @@ var OpenNewFileByByDateHour CheckTimeToOpenNewFileFunc = func(writer *FileWriter
 	return "", false
 }
 
+// ErrClosed FileWriter已经Close之后再写入返回
+var ErrClosed = errors.New("file writer is closed")
+
 type FileLogConf struct {
 	MaxFileSize int64
 }
@@ -70,9 +81,20 @@ type FileWriterConf struct {
 	ModuleName, BaseDir, FilePrefix string
 	SkipCall                        int
 	LogCfgLoader                    *logger.ConfLoader
-	CheckFileFullIntervalSec        int64
-	CheckTimeToOpenNewFile          CheckTimeToOpenNewFileFunc
-	BufChanLen                      uint32
+	CheckFileFullIntervalSec        int64                      // 检查文件大小以及文件是否被移走的间隔,0代表按已写入的大小判断,文件最多每秒检查一次
+	CheckTimeToOpenNewFile          CheckTimeToOpenNewFileFunc // Deprecated: 使用RotationPolicy,只在RotationPolicy为空时生效
+	RotationPolicy                  RotationPolicy             // 都没有设置时使用OpenNewFileByByDateHour,文件名与旧版本一致
+	BufChanLen                      uint32                     // Deprecated: 使用BufSizeBytes,未设置BufSizeBytes时按每行256字节估算缓冲大小
+	BufSizeBytes                    uint32                     // 缓冲区字节数,向上取整为2的幂
+	Format                          fmts.Format                // 日志格式,LogConf中配置了File.Format时以配置为准
+	BufFullPolicy                   BufFullPolicy              // 缓冲区写满时的处理策略,默认丢弃当前日志
+	BlockTimeout                    time.Duration              // BufFullPolicyBlock最长等待时间,默认1秒
+	SpillFile                       string                     // BufFullPolicySpill溢出文件路径,默认BaseDir/前缀.overflow.spill
+	CurrentLinkName                 string                     // 指向当前日志文件的软链接名,默认 前缀.current.txt
+	DisableCurrentLink              bool                       // 不维护指向当前日志文件的软链接
+	ReopenOnSIGHUP                  bool                       // 收到SIGHUP时重新打开日志文件
+	CrashSafeBuf                    bool                       // 缓冲区映射在文件上,进程崩溃后下次启动时恢复没有写入的日志
+	CrashSafeBufFile                string                     // CrashSafeBuf的映射文件路径,默认BaseDir/前缀.NameTag.buf.mmap,同一时间只能被一个进程使用
 	OnLogErr                        func(err error)
 }
 
@@ -86,9 +108,22 @@ func (c *FileWriterConf) Check() error {
 	if c.LogCfgLoader == nil {
 		return errors.New("LogCfgLoader is nil")
 	}
-	if c.CheckTimeToOpenNewFile == nil {
+	// 配置文件中不支持的格式已由ConfLoader回退到gzip,这里拒绝代码中传入的配置
+	if format := getCompressFormat(&c.LogCfgLoader.GetConf().File); format != "" {
+		if _, err := getCompressedFileSuffix(format); err != nil {
+			return err
+		}
+	}
+	// 保持旧的文件名,已有的外部工具和按文件名匹配的清理规则不受影响
+	if c.RotationPolicy == nil && c.CheckTimeToOpenNewFile == nil {
 		c.CheckTimeToOpenNewFile = OpenNewFileByByDateHour
 	}
+	if c.BufSizeBytes == 0 {
+		c.BufSizeBytes = defaultBufSizeBytes
+		if c.BufChanLen > 0 && uint64(c.BufChanLen)*estimatedLineBytes < defaultBufSizeBytes {
+			c.BufSizeBytes = c.BufChanLen * estimatedLineBytes
+		}
+	}
 	return nil
 }
 
@@ -97,32 +132,79 @@ func NewFileWriter(cfg *FileWriterConf) (*FileWriter, error) {
 	if err := cfg.Check(); err != nil {
 		return nil, err
 	}
-	return &FileWriter{
-		cfg:             cfg,
-		fmt:             fmts.NewTraceFormatter(cfg.ModuleName, cfg.SkipCall, fmts.FormatText, false, false, cfg.LogCfgLoader),
-		bufCh:           make(chan []byte, cfg.BufChanLen),
-		flushSignCh:     make(chan struct{}),
-		flushDoneSignCh: make(chan error),
-	}, nil
+	w := &FileWriter{
+		cfg:              cfg,
+		fmt:              fmts.NewTraceFormatter(cfg.ModuleName, cfg.SkipCall, cfg.Format, false, false, cfg.LogCfgLoader),
+		flushSignCh:      make(chan struct{}),
+		flushDoneSignCh:  make(chan error),
+		reopenSignCh:     make(chan struct{}),
+		reopenDoneSignCh: make(chan error),
+		closeSignCh:      make(chan struct{}),
+		closeDoneSignCh:  make(chan error),
+		loopDoneCh:       make(chan struct{}),
+		compressCh:       make(chan string, compressQueueLen),
+		compressStopCh:   make(chan struct{}),
+		compressDoneCh:   make(chan struct{}),
+	}
+
+	if !cfg.CrashSafeBuf {
+		w.queue = newByteRing(uint64(cfg.BufSizeBytes))
+		return w, nil
+	}
+
+	if err := os.MkdirAll(cfg.BaseDir, 0755); err != nil {
+		return nil, err
+	}
+	bufFile := w.getCrashSafeBufFile()
+	var err error
+	if w.queue, err = newMappedByteRing(bufFile, uint64(cfg.BufSizeBytes), func(recovered []byte, lines int) error {
+		return w.writeRecovered(bufFile, recovered, lines)
+	}); err != nil {
+		return nil, err
+	}
+
+	return w, nil
 }
 
 type FileWriter struct {
 	cfg                  *FileWriterConf
 	enabledStdoutPrinter atomic.Bool
 	fp                   *os.File
-	curSizeBytes         int64
+	curSizeBytes         atomic.Int64
 	lastCheckIsFullAt    int64
 	isFileFull           bool
 	isWrittenFullTip     bool
 	openCurFileTime      *time.Time
-	curFileName          string
+	curFileName          atomic.Value // string,压缩协程也会读取
 	fmt                  logger.Formatter
-	bufCh                chan []byte
+	queue                *byteRing
 	isFlushing           atomic.Bool
 	flushSignCh          chan struct{}
 	flushDoneSignCh      chan error
+	reopenSignCh         chan struct{}
+	reopenDoneSignCh     chan error
 	isHandlingExpiredLog atomic.Bool
 	lastFullBufChTipAt   atomic.Int64
+	sampler              sampler
+	closed               atomic.Bool
+	isLooping            bool
+	loopMu               sync.Mutex
+	pendingWrites        atomic.Int64 // 已经通过closed检查还没写入缓冲区的数量
+	closeSignCh          chan struct{}
+	closeDoneSignCh      chan error
+	loopDoneCh           chan struct{}
+	droppedLines         atomic.Int64 // 上次报告以来丢弃的日志行数
+	spilledLines         atomic.Int64 // 溢出文件中待回放的日志行数
+	spillFp              *os.File
+	spillMu              sync.Mutex
+	metrics              fileWriterMetrics
+	batch                []byte // Loop合并写入用的缓冲
+	rotation             rotationState
+	curPeriod            string
+	rotationCheckedAt    int64
+	compressCh           chan string // 待压缩的文件路径
+	compressStopCh       chan struct{}
+	compressDoneCh       chan struct{}
 }
 
 func (w *FileWriter) DisableCacheCaller(disabled bool) {
@@ -130,7 +212,13 @@ func (w *FileWriter) DisableCacheCaller(disabled bool) {
 }
 
 func (w *FileWriter) GetCurFileName() string {
-	return w.curFileName
+	curFileName, _ := w.curFileName.Load().(string)
+	return curFileName
+}
+
+// GetName logger名,用于LevelOverride.Loggers匹配
+func (w *FileWriter) GetName() string {
+	return w.cfg.FilePrefix
 }
 
 func (w *FileWriter) GetFilePrefix() string {
@@ -161,7 +249,7 @@ func (w *FileWriter) DisableStdoutPrinter() {
 }
 
 func (w *FileWriter) GetFileSize() int64 {
-	return w.curSizeBytes
+	return w.curSizeBytes.Load()
 }
 
 func (w *FileWriter) GetFileConf() logger.FileLogConf {
@@ -173,41 +261,86 @@ func (w *FileWriter) SetFormatter(fmt logger.Formatter) *FileWriter {
 	return w
 }
 
+// checkFileIsFull 定期检查文件大小,同时检查文件是否被删除、移走或者截断.
+// 没有设置CheckFileFullIntervalSec时每批都按已写入的大小判断是否写满,检查文件的系统调用最多每秒一次
 func (w *FileWriter) checkFileIsFull() (bool, error) {
-	if w.lastCheckIsFullAt != 0 && w.lastCheckIsFullAt+w.cfg.CheckFileFullIntervalSec > time.Now().Unix() {
+	now := time.Now().Unix()
+	interval := w.cfg.CheckFileFullIntervalSec
+	if interval <= 0 {
+		interval = defaultCheckFileIntervalSec
+	}
+	if w.lastCheckIsFullAt == 0 || w.lastCheckIsFullAt+interval <= now {
+		fileInfo, err := w.checkFileMoved()
+		if err != nil {
+			return false, err
+		}
+		w.curSizeBytes.Store(fileInfo.Size())
+		w.lastCheckIsFullAt = now
+	} else if w.cfg.CheckFileFullIntervalSec > 0 {
 		return w.isFileFull, nil
 	}
 
-	cfg := w.cfg.LogCfgLoader.GetConf()
-	if cfg.File.MaxFileSizeBytes > 0 {
-		fileName := w.fp.Name()
-		fileInfo, err := os.Stat(w.fp.Name())
-		if err != nil {
-			if !os.IsNotExist(err) {
-				return false, err
-			}
+	if maxFileSizeBytes := w.cfg.LogCfgLoader.GetConf().File.MaxFileSizeBytes; maxFileSizeBytes > 0 {
+		w.isFileFull = w.curSizeBytes.Load() >= maxFileSizeBytes
+	}
 
-			// 文件被删除了
-			if w.fp, err = os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0755); err != nil {
-				return false, err
-			}
+	return w.isFileFull, nil
+}
 
-			fileInfo, err = w.fp.Stat()
-			if err != nil {
-				return false, err
-			}
-		}
+// checkFileMoved 路径上的文件被删除、被logrotate等移走换成其他文件,或者被copytruncate截断时重新打开
+func (w *FileWriter) checkFileMoved() (os.FileInfo, error) {
+	fpInfo, err := w.fp.Stat()
+	if err != nil {
+		return nil, err
+	}
 
-		w.curSizeBytes = fileInfo.Size()
-		w.isFileFull = w.curSizeBytes >= cfg.File.MaxFileSizeBytes
-		w.lastCheckIsFullAt = time.Now().Unix()
+	pathInfo, err := os.Stat(w.fp.Name())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
 	}
 
-	return w.isFileFull, nil
+	if err == nil && os.SameFile(fpInfo, pathInfo) && pathInfo.Size() >= w.curSizeBytes.Load() {
+		return pathInfo, nil
+	}
+
+	if err = w.reopenFile(); err != nil {
+		return nil, err
+	}
+
+	return w.fp.Stat()
+}
+
+// reopenFile 关闭当前文件,按原来的路径重新打开
+func (w *FileWriter) reopenFile() error {
+	return w.openFile(w.GetCurFileName())
+}
+
+// Reopen 写完缓冲区中的日志后按原来的路径重新打开日志文件,用于配合外部的logrotate
+func (w *FileWriter) Reopen() error {
+	if w.closed.Load() {
+		return ErrClosed
+	}
+	select {
+	case w.reopenSignCh <- struct{}{}:
+		return <-w.reopenDoneSignCh
+	case <-w.loopDoneCh:
+		return ErrClosed
+	}
+}
+
+func (w *FileWriter) hdlReopen() error {
+	_, writeErr := w.writeQueued()
+	if w.fp == nil {
+		return writeErr
+	}
+	return errors.Join(writeErr, w.reopenFile())
 }
 
 func (w *FileWriter) tryOpenNewFile() error {
-	var err error
+	if w.cfg.RotationPolicy != nil {
+		return w.tryRotate()
+	}
+
 	fileName, ok := w.cfg.CheckTimeToOpenNewFile(w, w.openCurFileTime, w.openCurFileTime == nil)
 	if !ok {
 		if w.fp == nil {
@@ -217,6 +350,12 @@ func (w *FileWriter) tryOpenNewFile() error {
 		return nil
 	}
 
+	return w.openFile(fileName)
+}
+
+// openFile 打开日志文件并关闭之前的文件
+func (w *FileWriter) openFile(fileName string) error {
+	var err error
 	if w.fp == nil {
 		_, err = os.Stat(w.cfg.BaseDir)
 		if err != nil {
@@ -229,30 +368,145 @@ func (w *FileWriter) tryOpenNewFile() error {
 		}
 	}
 
-	if w.fp, err = os.OpenFile(w.cfg.BaseDir+"/"+fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0755); err != nil {
+	fp, err := os.OpenFile(w.cfg.BaseDir+"/"+fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0755)
+	if err != nil {
 		return err
 	}
 
-	fileInfo, err := w.fp.Stat()
+	fileInfo, err := fp.Stat()
 	if err != nil {
+		_ = fp.Close()
 		return err
 	}
 
-	w.curSizeBytes = fileInfo.Size()
+	prevFileName := w.GetCurFileName()
+	if w.fp != nil {
+		if prevFileName != fileName {
+			w.metrics.rotationCount.Add(1)
+		}
+		// 新文件已经打开,关闭旧文件失败不影响写日志
+		if err = w.fp.Close(); err != nil {
+			w.hdlLogErr(err)
+		}
+	}
+
+	w.fp = fp
+	w.curSizeBytes.Store(fileInfo.Size())
 	openFileTime := time.Now()
 	w.openCurFileTime = &openFileTime
 	w.isFileFull = false
 	w.lastCheckIsFullAt = 0
-	w.curFileName = fileName
+	w.curFileName.Store(fileName)
+
+	if !w.cfg.DisableCurrentLink {
+		// 软链接失败不影响写日志
+		if err = w.updateCurrentLink(fileName); err != nil {
+			w.hdlLogErr(err)
+		}
+	}
+
+	// 切换后的分段交给压缩协程,是否满足压缩条件由压缩协程判断
+	if prevFileName != "" && prevFileName != fileName {
+		w.enqueueCompress(w.cfg.BaseDir + "/" + prevFileName)
+	}
 
 	return nil
 }
 
+// getCrashSafeBufFile 默认文件名带上RotationPolicy的NameTag,共用目录和前缀的多个节点各用一个文件
+func (w *FileWriter) getCrashSafeBufFile() string {
+	if w.cfg.CrashSafeBufFile != "" {
+		return w.cfg.CrashSafeBufFile
+	}
+	name := w.getFilePrefix()
+	if policy, ok := w.cfg.RotationPolicy.(*TimeSizeRotation); ok && policy.NameTag != "" {
+		name += policy.NameTag + "."
+	}
+	return w.cfg.BaseDir + "/" + name + ringFileDefaultName
+}
+
+func (w *FileWriter) getCurrentLinkName() string {
+	if w.cfg.CurrentLinkName != "" {
+		return w.cfg.CurrentLinkName
+	}
+	return w.getFilePrefix() + defaultCurrentLinkName
+}
+
+// updateCurrentLink 先创建临时软链接再rename覆盖,tail -F等工具不会看到软链接缺失
+func (w *FileWriter) updateCurrentLink(fileName string) error {
+	linkPath := w.cfg.BaseDir + "/" + w.getCurrentLinkName()
+	tmpLinkPath := linkPath + ".tmp"
+	_ = os.Remove(tmpLinkPath)
+	// 相对路径,目录整体移动后仍然有效
+	if err := os.Symlink(fileName, tmpLinkPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpLinkPath, linkPath); err != nil {
+		_ = os.Remove(tmpLinkPath)
+		return err
+	}
+	return nil
+}
+
+// IsLoggable 不知道调用方,存在调用方级别覆盖时按其中最低级别判断
 func (w *FileWriter) IsLoggable(level logger.Level) bool {
-	if level < w.cfg.LogCfgLoader.GetConf().File.GetLevel() {
+	minLevel := w.getLoggerLevel()
+	if overrides := w.cfg.LogCfgLoader.GetLevelOverrides(); overrides.HasCallerOverrides() && overrides.GetMinCallerLevel() < minLevel {
+		minLevel = overrides.GetMinCallerLevel()
+	}
+	if level < minLevel {
 		return false
 	}
 
+	return w.isUnderSizeLimit(level)
+}
+
+// isLoggableByCaller 按调用方覆盖级别和调用点采样判断,pc为0时按skipCall查找调用方,
+// 必须和appendFormat处于同一调用深度,这样skipCall取到的调用方才一致
+func (w *FileWriter) isLoggableByCaller(level logger.Level, pc uintptr, skipCall int, args []interface{}) bool {
+	minLevel := w.getLoggerLevel()
+	overrides := w.cfg.LogCfgLoader.GetLevelOverrides()
+	fileCfg := &w.cfg.LogCfgLoader.GetConf().File
+	needCallerLevel := overrides.HasCallerOverrides()
+	needSample := isSampleEnabled(fileCfg) && level <= getSampleMaxLevel(fileCfg)
+
+	if needCallerLevel || needSample {
+		if level < minLevel && (!needCallerLevel || level < overrides.GetMinCallerLevel()) {
+			return false
+		}
+		if pc == 0 {
+			pc = callerPC(skipCall+1, args)
+		}
+	}
+
+	if needCallerLevel && pc != 0 {
+		if callerLevel, ok := overrides.GetCallerLevel(pc); ok {
+			minLevel = callerLevel
+		}
+	}
+	if level < minLevel {
+		return false
+	}
+
+	if !w.isUnderSizeLimit(level) {
+		return false
+	}
+
+	if needSample && pc != 0 {
+		return w.sampler.check(pc, fileCfg)
+	}
+
+	return true
+}
+
+func (w *FileWriter) getLoggerLevel() logger.Level {
+	if level, ok := w.cfg.LogCfgLoader.GetLevelOverrides().GetLoggerLevel(w.GetName()); ok {
+		return level
+	}
+	return w.cfg.LogCfgLoader.GetConf().File.GetLevel()
+}
+
+func (w *FileWriter) isUnderSizeLimit(level logger.Level) bool {
 	limitedBytes := int64(-1)
 	switch level {
 	case logger.LevelDebug:
@@ -260,26 +514,66 @@ func (w *FileWriter) IsLoggable(level logger.Level) bool {
 	case logger.LevelInfo:
 		limitedBytes = w.cfg.LogCfgLoader.GetConf().File.LogInfoBeforeFileSizeBytes
 	}
-	if limitedBytes >= 0 && w.curSizeBytes >= limitedBytes {
+	if limitedBytes >= 0 && w.curSizeBytes.Load() >= limitedBytes {
 		return false
 	}
 
 	return true
 }
 
-func (w *FileWriter) asyncWrite(logContent []byte) {
-	select {
-	case w.bufCh <- logContent:
-	default:
-		if time.Now().Unix()-w.lastFullBufChTipAt.Load() > 5 {
-			fmt.Println("log chan is full, content:", string(logContent))
-			w.lastFullBufChTipAt.Store(time.Now().Unix())
-		}
+// appendFormat 格式化追加到b,skipCall从appendFormat的调用方算起.
+// formatter没有实现logger.AppendFormatter时调用Sprintf,skipCall不一致时和以前一样复制一份formatter
+func (w *FileWriter) appendFormat(b []byte, level logger.Level, stdoutColor logger.Color, skipCall int, args ...interface{}) ([]byte, error) {
+	if appender, ok := w.fmt.(logger.AppendFormatter); ok {
+		return appender.AppendFormat(b, level, stdoutColor, skipCall+1, args...)
 	}
+
+	fm := w.fmt
+	if fm.GetSkipCall() != skipCall+1 {
+		fm = w.fmt.Copy()
+		fm.SetSkipCall(skipCall + 1)
+	}
+	formatted, err := fm.Sprintf(level, stdoutColor, args...)
+	if err != nil {
+		return b, err
+	}
+	return append(b, formatted...), nil
+}
+
+// asyncWrite 日志拷贝进缓冲区后buf放回池中,调用方之后不能再使用
+func (w *FileWriter) asyncWrite(buf *buffer) error {
+	defer buf.free()
+
+	w.pendingWrites.Add(1)
+	defer w.pendingWrites.Add(-1)
+
+	if w.closed.Load() {
+		return ErrClosed
+	}
+
+	if w.queue.tryPush(buf.b) {
+		w.metrics.accept(len(buf.b), w.queue.usedBytes())
+		return nil
+	}
+	w.hdlBufFull(buf.b)
+
+	return nil
+}
+
+// callerPC args携带了调用方pc时直接使用,否则按skipCall查找,skipCall的算法与isLoggableByCaller相同
+func callerPC(skipCall int, args []interface{}) uintptr {
+	if _, extra := logger.SplitExtra(args); extra != nil && extra.PC != 0 {
+		return extra.PC
+	}
+	var rpc [1]uintptr
+	if runtime.Callers(skipCall+1, rpc[:]) > 0 {
+		return rpc[0]
+	}
+	return 0
 }
 
 func (w *FileWriter) WriteBySkipCall(level logger.Level, skipCall int, args ...interface{}) error {
-	if !w.IsLoggable(level) {
+	if !w.isLoggableByCaller(level, 0, skipCall, args) {
 		return nil
 	}
 
@@ -288,28 +582,22 @@ func (w *FileWriter) WriteBySkipCall(level logger.Level, skipCall int, args ...i
 		stdoutColor = logger.ColorNil
 	}
 
-	fm := w.fmt
-	if w.fmt.GetSkipCall() != skipCall {
-		fm = w.fmt.Copy()
-		fm.SetSkipCall(skipCall)
-	}
-
-	logContent, err := fm.Sprintf(level, stdoutColor, args...)
-	if err != nil {
+	buf := getBuffer()
+	var err error
+	if buf.b, err = w.appendFormat(buf.b, level, stdoutColor, skipCall, args...); err != nil {
+		buf.free()
 		return err
 	}
 
 	if w.enabledStdoutPrinter.Load() {
-		fmt.Print(string(logContent))
+		_, _ = os.Stdout.Write(buf.b)
 	}
 
-	w.asyncWrite(logContent)
-
-	return nil
+	return w.asyncWrite(buf)
 }
 
 func (w *FileWriter) Write(level logger.Level, args ...interface{}) error {
-	if !w.IsLoggable(level) {
+	if !w.isLoggableByCaller(level, 0, w.fmt.GetSkipCall(), args) {
 		return nil
 	}
 
@@ -318,28 +606,29 @@ func (w *FileWriter) Write(level logger.Level, args ...interface{}) error {
 		stdoutColor = logger.ColorNil
 	}
 
-	logContent, err := w.fmt.Sprintf(level, stdoutColor, args...)
-	if err != nil {
+	buf := getBuffer()
+	var err error
+	if buf.b, err = w.appendFormat(buf.b, level, stdoutColor, w.fmt.GetSkipCall(), args...); err != nil {
+		buf.free()
 		return err
 	}
 
 	if w.enabledStdoutPrinter.Load() {
-		fmt.Print(logContent)
+		_, _ = os.Stdout.Write(buf.b)
 	}
 
-	w.asyncWrite(logContent)
-
-	return nil
+	return w.asyncWrite(buf)
 }
 
 func (w *FileWriter) WriteMsg(msg *logger.Msg) error {
-	if !w.IsLoggable(msg.Level) {
+	if !w.isLoggableByCaller(msg.Level, msg.PC, msg.SkipCall, nil) {
 		return nil
 	}
 
-	w.asyncWrite(msg.Formatted)
-
-	return nil
+	// msg可能被多个writer共享,拷贝一份
+	buf := getBuffer()
+	buf.b = append(buf.b, msg.Formatted...)
+	return w.asyncWrite(buf)
 }
 
 func (w *FileWriter) GetMsg(level logger.Level, args ...interface{}) (*logger.Msg, error) {
@@ -348,7 +637,7 @@ func (w *FileWriter) GetMsg(level logger.Level, args ...interface{}) (*logger.Ms
 		stdoutColor = logger.ColorNil
 	}
 
-	formatted, err := w.fmt.Sprintf(level, stdoutColor, args...)
+	formatted, err := w.appendFormat(nil, level, stdoutColor, w.fmt.GetSkipCall(), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -356,7 +645,9 @@ func (w *FileWriter) GetMsg(level logger.Level, args ...interface{}) (*logger.Ms
 	return &logger.Msg{
 		Level:     level,
 		SkipCall:  w.fmt.GetSkipCall(),
+		PC:        callerPC(w.fmt.GetSkipCall(), args),
 		Formatted: formatted,
+		Fields:    logger.GetExtraFields(args),
 	}, nil
 }
 
@@ -366,13 +657,7 @@ func (w *FileWriter) GetMsgBySkipCall(level logger.Level, skipCall int, args ...
 		stdoutColor = logger.ColorNil
 	}
 
-	fm := w.fmt
-	if w.fmt.GetSkipCall() != skipCall {
-		fm = w.fmt.Copy()
-		fm.SetSkipCall(skipCall)
-	}
-
-	formatted, err := fm.Sprintf(level, stdoutColor, args...)
+	formatted, err := w.appendFormat(nil, level, stdoutColor, skipCall, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -380,17 +665,57 @@ func (w *FileWriter) GetMsgBySkipCall(level logger.Level, skipCall int, args ...
 	return &logger.Msg{
 		Level:     level,
 		SkipCall:  skipCall,
+		PC:        callerPC(skipCall, args),
 		Formatted: formatted,
+		Fields:    logger.GetExtraFields(args),
 	}, nil
 }
 
 func (w *FileWriter) Flush() error {
+	if w.closed.Load() {
+		return ErrClosed
+	}
 	w.isFlushing.Store(true)
-	w.flushSignCh <- struct{}{}
-	return <-w.flushDoneSignCh
+	select {
+	case w.flushSignCh <- struct{}{}:
+		return <-w.flushDoneSignCh
+	case <-w.loopDoneCh:
+		w.isFlushing.Store(false)
+		return ErrClosed
+	}
+}
+
+// Close 停止接收写入,把缓冲区中剩余日志写完后sync并关闭文件,Loop随之返回.之后的写入返回ErrClosed
+func (w *FileWriter) Close() error {
+	w.loopMu.Lock()
+	if !w.closed.CompareAndSwap(false, true) {
+		w.loopMu.Unlock()
+		return ErrClosed
+	}
+	isLooping := w.isLooping
+	w.loopMu.Unlock()
+
+	// Loop没有运行,由Close自己写完剩余日志
+	if !isLooping {
+		return w.drainAndClose()
+	}
+
+	select {
+	case w.closeSignCh <- struct{}{}:
+		return <-w.closeDoneSignCh
+	case <-w.loopDoneCh:
+		return nil
+	}
+}
+
+func (w *FileWriter) IsClosed() bool {
+	return w.closed.Load()
 }
 
 func (w *FileWriter) finishFlush(err error) {
+	if err != nil {
+		w.metrics.errCount.Add(1)
+	}
 	w.isFlushing.Store(false)
 	w.flushDoneSignCh <- err
 }
@@ -423,7 +748,7 @@ func (w *FileWriter) hdlExpiredFiles() {
 				return nil
 			}
 
-			if strings.HasPrefix(filepath.Base(path), w.getFilePrefix()) && (strings.HasSuffix(path, FileSuffix) || strings.HasSuffix(path, CompressedFileSuffix)) {
+			if w.isLogFile(path) {
 				files = append(files, &info)
 				mapFileToPath[&info] = path
 			}
@@ -461,7 +786,7 @@ func (w *FileWriter) hdlExpiredFiles() {
 		}
 
 		if logCfg.FileMaxRemainDays > 0 && info.ModTime().Unix() < (time.Now().Unix()-3600*24*int64(logCfg.FileMaxRemainDays)) {
-			if strings.HasPrefix(filepath.Base(path), w.getFilePrefix()) && (strings.HasSuffix(path, FileSuffix) || strings.HasSuffix(path, CompressedFileSuffix)) {
+			if w.isLogFile(path) {
 				if err := os.Remove(path); err != nil {
 					fmt.Println(err)
 				}
@@ -469,115 +794,260 @@ func (w *FileWriter) hdlExpiredFiles() {
 			}
 		}
 
-		if logCfg.CompressFrequentHours > 0 && info.ModTime().Unix() < (time.Now().Unix()-3600*int64(logCfg.CompressFrequentHours)) {
-			if logCfg.CompressAfterReachBytes > 0 && info.Size() < logCfg.CompressAfterReachBytes {
-				return nil
-			}
-
-			if strings.HasPrefix(filepath.Base(path), w.getFilePrefix()) && strings.HasSuffix(path, FileSuffix) {
-				file, err := os.OpenFile(path, os.O_RDWR, os.ModePerm)
-				if err != nil {
-					fmt.Println(err)
-					return nil
-				}
+		if w.shouldCompress(path, info, &logCfg) {
+			w.enqueueCompress(path)
+		}
 
-				defer file.Close()
+		return nil
+	})
 
-				err = gofiler.Zip([]*os.File{file}, file.Name()+".zip")
-				if err != nil {
-					fmt.Println(err)
-					return nil
-				}
+	if logCfg.MaxTotalBytes > 0 {
+		w.removeFilesOverTotalBytes(logCfg.MaxTotalBytes)
+	}
+}
 
-				if err := os.Remove(path); err != nil {
-					fmt.Println(err)
-				}
+// doWriteMoreAsPossible 把缓冲区中已有的日志合并在buf之后一起写入,合并用的缓冲复用
+func (w *FileWriter) doWriteMoreAsPossible(buf []byte) error {
+	w.batch = append(w.batch[:0], buf...)
+	w.batch = w.queue.drain(w.batch, batchWriteBytes)
+	return w.writeBatch()
+}
 
-				return nil
-			}
+// writeQueued 分批写完缓冲区中所有已提交的日志,返回是否写了日志
+func (w *FileWriter) writeQueued() (bool, error) {
+	var (
+		written bool
+		errs    []error
+	)
+	for {
+		w.batch = w.queue.drain(w.batch[:0], batchWriteBytes)
+		if len(w.batch) == 0 {
+			// 没有日志或者最早的日志还没提交完,提交时会再次通知
+			break
 		}
+		written = true
+		if err := w.writeBatch(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return written, errors.Join(errs...)
+}
 
-		return nil
-	})
+// writeBatch 写入成功后才释放batch在缓冲区中的空间,映射文件的缓冲区崩溃时可以恢复没写成功的日志
+func (w *FileWriter) writeBatch() error {
+	err := w.writeBuf(w.batch)
+	if err == nil {
+		w.queue.commitRead()
+	}
+	if cap(w.batch) > maxPooledBufferBytes {
+		w.batch = nil
+	}
+	return err
 }
 
-func (w *FileWriter) Loop() {
-	doWriteMoreAsPossible := func(buf []byte) error {
-		for {
-			var moreBuf []byte
-			select {
-			case moreBuf = <-w.bufCh:
-				buf = append(buf, moreBuf...)
-			default:
-			}
+func (w *FileWriter) writeBuf(buf []byte) error {
+	if len(buf) == 0 {
+		return nil
+	}
 
-			if moreBuf == nil || len(buf) > 1024*16 {
-				break
-			}
-		}
+	if err := w.tryOpenNewFile(); err != nil {
+		return err
+	}
+
+	isFull, err := w.checkFileIsFull()
+	if err != nil {
+		return err
+	}
 
-		if len(buf) == 0 {
+	if isFull {
+		if w.isWrittenFullTip {
 			return nil
 		}
+		buf = []byte(fmt.Sprintf("%s文件已超出当前小时允许最大尺寸:%d bytes!!!\u001B[0m\n", logger.ColorToStdoutMap[logger.ColorPurple], w.cfg.LogCfgLoader.GetConf().File.MaxFileSizeBytes))
+	}
 
-		if err := w.tryOpenNewFile(); err != nil {
-			return err
-		}
+	w.isWrittenFullTip = isFull
 
-		isFull, err := w.checkFileIsFull()
+	writeStartAt := time.Now()
+	defer func() {
+		w.metrics.observeWrite(time.Since(writeStartAt))
+	}()
+
+	bufLen := len(buf)
+	var totalWrittenBytes int
+	for {
+		n, err := w.fp.Write(buf[totalWrittenBytes:])
 		if err != nil {
 			return err
 		}
-
-		if isFull {
-			if w.isWrittenFullTip {
-				return nil
-			}
-			buf = []byte(fmt.Sprintf("%s文件已超出当前小时允许最大尺寸:%d bytes!!!\u001B[0m\n", logger.ColorToStdoutMap[logger.ColorPurple], w.cfg.LogCfgLoader.GetConf().File.MaxFileSizeBytes))
+		totalWrittenBytes += n
+		if totalWrittenBytes >= bufLen {
+			break
 		}
+	}
+	w.curSizeBytes.Add(int64(totalWrittenBytes))
 
-		w.isWrittenFullTip = isFull
+	return nil
+}
 
-		bufLen := len(buf)
-		var totalWrittenBytes int
-		for {
-			n, err := w.fp.Write(buf[totalWrittenBytes:])
-			if err != nil {
-				return err
-			}
-			totalWrittenBytes += n
-			if totalWrittenBytes >= bufLen {
-				break
-			}
-		}
+func (w *FileWriter) Loop() {
+	defer close(w.loopDoneCh)
+	w.loopMu.Lock()
+	if w.closed.Load() {
+		w.loopMu.Unlock()
+		return
+	}
+	w.isLooping = true
+	w.loopMu.Unlock()
 
-		return nil
+	if err := w.tryOpenNewFile(); err != nil {
+		w.hdlLogErr(err)
 	}
 
-	if err := w.tryOpenNewFile(); err != nil && w.cfg.OnLogErr != nil {
-		w.cfg.OnLogErr(err)
+	if err := w.replaySpill(); err != nil {
+		w.hdlLogErr(err)
+	}
+
+	go w.compressLoop()
+
+	var sighupCh chan os.Signal
+	if w.cfg.ReopenOnSIGHUP {
+		sighupCh = make(chan os.Signal, 1)
+		signal.Notify(sighupCh, syscall.SIGHUP)
+		defer signal.Stop(sighupCh)
 	}
 
 	dealExpiredFilesTk := time.NewTicker(time.Minute * 10)
 	defer dealExpiredFilesTk.Stop()
+	reportTk := time.NewTicker(reportInterval)
+	defer reportTk.Stop()
 	for {
 		select {
-		case buf := <-w.bufCh:
-			if err := doWriteMoreAsPossible(buf); err != nil && w.cfg.OnLogErr != nil {
-				w.cfg.OnLogErr(err)
+		case <-w.queue.notifyCh:
+			if _, err := w.writeQueued(); err != nil {
+				w.hdlLogErr(err)
+			}
+			if w.spilledLines.Load() > 0 && w.queue.len() == 0 {
+				if err := w.replaySpill(); err != nil {
+					w.hdlLogErr(err)
+				}
 			}
 		case <-w.flushSignCh:
-			if err := doWriteMoreAsPossible([]byte{}); err != nil {
+			if err := w.doWriteMoreAsPossible([]byte{}); err != nil {
 				w.finishFlush(err)
 				break
 			}
-			if err := w.fp.Sync(); err != nil {
+			if err := w.syncFile(); err != nil {
 				w.finishFlush(err)
 				break
 			}
 			w.finishFlush(nil)
+		case <-w.reopenSignCh:
+			w.reopenDoneSignCh <- w.hdlReopen()
+		case <-sighupCh:
+			if err := w.hdlReopen(); err != nil {
+				w.hdlLogErr(err)
+			}
 		case <-dealExpiredFilesTk.C:
 			go w.hdlExpiredFiles()
+		case <-reportTk.C:
+			if err := w.replaySpill(); err != nil {
+				w.hdlLogErr(err)
+			}
+			if report := append(w.takeDroppedReport(), w.sampler.takeSampledReport(w.appendNotice)...); len(report) > 0 {
+				if err := w.doWriteMoreAsPossible(report); err != nil {
+					w.hdlLogErr(err)
+				}
+			}
+		case <-w.closeSignCh:
+			err := w.drainAndClose()
+			close(w.compressStopCh)
+			<-w.compressDoneCh
+			w.closeDoneSignCh <- err
+			return
 		}
 	}
 }
+
+func (w *FileWriter) drainAndClose() error {
+	var errs []error
+
+	// 等待已经通过closed检查的写入完成,之后缓冲区不会再有新数据.等待期间继续消费缓冲区,避免阻塞策略的写入一直等到超时
+	for w.pendingWrites.Load() > 0 {
+		written, err := w.writeQueued()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !written {
+			runtime.Gosched()
+		}
+	}
+
+	if _, err := w.writeQueued(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := w.replaySpill(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := w.closeSpillFile(); err != nil {
+		errs = append(errs, err)
+	}
+	if report := append(w.takeDroppedReport(), w.sampler.takeSampledReport(w.appendNotice)...); len(report) > 0 {
+		if err := w.doWriteMoreAsPossible(report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if w.fp != nil {
+		if err := w.syncFile(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := w.fp.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := w.queue.close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	w.metrics.errCount.Add(int64(len(errs)))
+
+	return errors.Join(errs...)
+}
+
+// writeRecovered 在恢复的日志前加上标记行,写在本次启动的日志之前.
+// 恢复的日志只在内存中,落盘之后才能重置缓冲文件
+func (w *FileWriter) writeRecovered(bufFile string, recovered []byte, lines int) error {
+	b := w.appendNotice(nil, "recovered after crash: %d lines from %s", lines, bufFile)
+	if err := w.writeBuf(append(b, recovered...)); err != nil {
+		return err
+	}
+	return w.syncFile()
+}
+
+func (w *FileWriter) syncFile() error {
+	syncStartAt := time.Now()
+	defer func() {
+		w.metrics.observeSync(time.Since(syncStartAt))
+	}()
+	return w.fp.Sync()
+}
+
+// appendNotice 把writer自身的统计和维护信息按当前格式格式化为一条WARN日志追加到b,json/logfmt格式下仍然每行一条日志
+func (w *FileWriter) appendNotice(b []byte, format string, args ...interface{}) []byte {
+	formatted, err := w.appendFormat(b, logger.LevelWarn, levelToStdoutColorMap[logger.LevelWarn], 2, append([]interface{}{format}, args...)...)
+	if err != nil {
+		fmt.Println(err)
+		return b
+	}
+	return formatted
+}
+
+// hdlLogErr 记录错误数并回调OnLogErr
+func (w *FileWriter) hdlLogErr(err error) {
+	w.metrics.errCount.Add(1)
+	if w.cfg.OnLogErr != nil {
+		w.cfg.OnLogErr(err)
+	}
+}
@@ -1,12 +1,59 @@
 package writer
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
 )
 
+// newTestConfLoader 不读取配置文件的ConfLoader
+func newTestConfLoader(t testing.TB, logCfg *logger.LogConf) *logger.ConfLoader {
+	t.Helper()
+	cfgLoader, err := logger.NewConfLoader("", 10, logCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfgLoader
+}
+
+// newTestFileWriter conf没有设置BaseDir/SkipCall/LogCfgLoader时使用临时目录、logger调用的层数和INFO级别不限大小的配置
+func newTestFileWriter(t testing.TB, conf *FileWriterConf) *FileWriter {
+	t.Helper()
+	if conf.BaseDir == "" {
+		conf.BaseDir = t.TempDir()
+	}
+	if conf.SkipCall == 0 {
+		conf.SkipCall = 4
+	}
+	if conf.LogCfgLoader == nil {
+		conf.LogCfgLoader = newTestConfLoader(t, &logger.LogConf{
+			File: logger.FileLogConf{Level: "INFO", LogInfoBeforeFileSizeBytes: -1},
+		})
+	}
+	fileWriter, err := NewFileWriter(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileWriter
+}
+
+// linePadding 让每行日志在200字节左右,512字节的缓冲区正好放下2行
+var linePadding = strings.Repeat("x", 100)
+
+func popLine(w *FileWriter) string {
+	line, _ := w.queue.pop(nil)
+	return string(line)
+}
+
 func TestWriteBufChan(t *testing.T) {
 	bufCh := make(chan []byte, 100)
 	for i := 0; i < 101; i++ {
@@ -35,3 +82,336 @@ func TestNewFile(t *testing.T) {
 	}
 	time.Sleep(time.Second)
 }
+
+func TestCallerLevelOverride(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		LogCfgLoader: newTestConfLoader(t, &logger.LogConf{
+			File: logger.FileLogConf{Level: "ERR", LogDebugBeforeFileSizeBytes: -1},
+			LevelOverride: logger.LevelOverrideConf{
+				Callers: map[string]string{"github.com/995933447/log-go/v2/loggo/logger/writer.TestCallerLevelOverride": "DBG"},
+			},
+		}),
+		BufChanLen: 10,
+	})
+
+	logger.NewLogger(fileWriter).Debug("enabled by caller override")
+	logDebugNotMatched(fileWriter)
+	if fileWriter.queue.len() != 1 {
+		t.Fatalf("expect 1 line, got %d", fileWriter.queue.len())
+	}
+	if line := popLine(fileWriter); !strings.Contains(line, "TestCallerLevelOverride:file_test.go") {
+		t.Fatalf("unexpected line: %s", line)
+	}
+}
+
+func logDebugNotMatched(fileWriter *FileWriter) {
+	logger.NewLogger(fileWriter).Debug("not matched")
+}
+
+// sprintfFormatter 只实现了logger.Formatter,没有AppendFormat
+type sprintfFormatter struct {
+	logger.Formatter
+}
+
+func TestSprintfFormatter(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{BufChanLen: 10})
+	fileWriter.SetFormatter(sprintfFormatter{fmts.NewTraceFormatter("", 4, fmts.FormatText, true, false, fileWriter.cfg.LogCfgLoader)})
+
+	logger.NewLogger(fileWriter).Info("by logger")
+	if err := fileWriter.WriteBySkipCall(logger.LevelInfo, 2, "by skip call"); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"by logger", "by skip call"} {
+		if line := popLine(fileWriter); !strings.Contains(line, "TestSprintfFormatter:file_test.go") || !strings.Contains(line, msg) {
+			t.Fatalf("unexpected line: %s", line)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{BufChanLen: 100})
+	loopDone := make(chan struct{})
+	go func() {
+		fileWriter.Loop()
+		close(loopDone)
+	}()
+
+	fileLogger := logger.NewLogger(fileWriter)
+	for i := 0; i < 50; i++ {
+		fileLogger.Infof("line %d", i)
+	}
+	if err := fileLogger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-loopDone:
+	case <-time.After(time.Second):
+		t.Fatal("Loop not returned after Close")
+	}
+
+	content, err := os.ReadFile(fileWriter.cfg.BaseDir + "/" + fileWriter.GetCurFileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(content), "\n"); n != 50 {
+		t.Fatalf("expect 50 lines, got %d", n)
+	}
+	if err = fileWriter.Write(logger.LevelInfo, "after close"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expect ErrClosed, got %v", err)
+	}
+	if err = fileWriter.Flush(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expect ErrClosed, got %v", err)
+	}
+	if err = fileWriter.Close(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expect ErrClosed, got %v", err)
+	}
+}
+
+// expectFileLines path中的行数为len(lines),且按顺序包含lines
+func expectFileLines(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(got) != len(lines) {
+		t.Fatalf("expect %d lines in %s, got %q", len(lines), path, content)
+	}
+	for i, line := range lines {
+		if !strings.Contains(got[i], line) {
+			t.Fatalf("line %d of %s should contain %s, got %q", i, path, line, content)
+		}
+	}
+}
+
+func TestReopen(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		FilePrefix:     "app",
+		RotationPolicy: NewSizeRotation(0),
+	})
+	go fileWriter.Loop()
+	defer fileWriter.Close()
+
+	writeLine := func(line string) {
+		if err := fileWriter.Write(logger.LevelInfo, line); err != nil {
+			t.Fatal(err)
+		}
+		if err := fileWriter.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := fileWriter.cfg.BaseDir + "/app.001.txt"
+
+	// logrotate移走文件后调用Reopen
+	writeLine("before move")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileWriter.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	writeLine("after reopen")
+	expectFileLines(t, path+".1", "before move")
+	expectFileLines(t, path, "after reopen")
+
+	if stats := fileWriter.Stats(); stats.RotationCount != 0 {
+		t.Fatalf("reopen should not count as rotation, got %d", stats.RotationCount)
+	}
+}
+
+func TestCheckFileMoved(t *testing.T) {
+	// 不启动Loop,直接调整上次检查的时间,不用等待检查间隔
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		FilePrefix:     "app",
+		RotationPolicy: NewSizeRotation(0),
+	})
+	writeLine := func(line string) {
+		if err := fileWriter.Write(logger.LevelInfo, line); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fileWriter.writeQueued(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := fileWriter.cfg.BaseDir + "/app.001.txt"
+
+	// 没有调用Reopen,定期检查发现inode变化
+	writeLine("before rename")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileWriter.lastCheckIsFullAt = time.Now().Unix()
+	writeLine("within interval")
+	fileWriter.lastCheckIsFullAt -= defaultCheckFileIntervalSec
+	writeLine("after rename")
+	expectFileLines(t, path+".1", "before rename", "within interval")
+	expectFileLines(t, path, "after rename")
+
+	// copytruncate
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	fileWriter.lastCheckIsFullAt -= defaultCheckFileIntervalSec
+	writeLine("after truncate")
+	expectFileLines(t, path, "after truncate")
+
+	if stats := fileWriter.Stats(); stats.RotationCount != 0 {
+		t.Fatalf("reopen should not count as rotation, got %d", stats.RotationCount)
+	}
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReopenOnSIGHUP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP is not supported on windows")
+	}
+	// 测试自己也接收SIGHUP,Loop还没开始监听时进程不会被信号结束
+	sighupCh := make(chan os.Signal, 1)
+	signal.Notify(sighupCh, syscall.SIGHUP)
+	defer signal.Stop(sighupCh)
+
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		FilePrefix:     "app",
+		RotationPolicy: NewSizeRotation(0),
+		ReopenOnSIGHUP: true,
+	})
+	go fileWriter.Loop()
+	defer fileWriter.Close()
+
+	path := fileWriter.cfg.BaseDir + "/app.001.txt"
+	if err := fileWriter.Write(logger.LevelInfo, "before move"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileWriter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	proc, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Loop开始监听SIGHUP之前发出的信号会被忽略,重新打开之前一直重发
+	for deadline := time.Now().Add(time.Second * 5); ; {
+		if err := proc.Signal(syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		<-sighupCh
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("log file should be reopened after SIGHUP")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if err := fileWriter.Write(logger.LevelInfo, "after sighup"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileWriter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expectFileLines(t, path+".1", "before move")
+	expectFileLines(t, path, "after sighup")
+}
+
+func TestCrashSafeBuf(t *testing.T) {
+	baseDir := t.TempDir()
+	cfgLoader := newTestConfLoader(t, &logger.LogConf{
+		File: logger.FileLogConf{Level: "INFO", LogInfoBeforeFileSizeBytes: -1},
+	})
+	newConf := func() *FileWriterConf {
+		return &FileWriterConf{
+			BaseDir:        baseDir,
+			FilePrefix:     "app",
+			SkipCall:       4,
+			LogCfgLoader:   cfgLoader,
+			RotationPolicy: &TimeSizeRotation{NameTag: "7"},
+			CrashSafeBuf:   true,
+		}
+	}
+
+	// 日志还在缓冲区中时进程崩溃
+	crashed := newTestFileWriter(t, newConf())
+	if _, err := os.Stat(baseDir + "/app.7.buf.mmap"); err != nil {
+		t.Fatalf("buf file name should contain the name tag, %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := crashed.Write(logger.LevelInfo, "line %d", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 进程还活着时不能被另一个进程恢复
+	if _, err := NewFileWriter(newConf()); !errors.Is(err, ErrRingFileLocked) {
+		t.Fatalf("expect ErrRingFileLocked, got %v", err)
+	}
+	if err := crashed.queue.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fileWriter := newTestFileWriter(t, newConf())
+	// 重置缓冲文件之前恢复的日志已经写进日志文件
+	if content, err := os.ReadFile(baseDir + "/app.7_001.txt"); err != nil || !strings.Contains(string(content), "line 2") {
+		t.Fatalf("recovered lines should be written on start, got %q %v", content, err)
+	}
+	if err := fileWriter.Write(logger.LevelInfo, "after restart"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(baseDir + "/app.7_001.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], "recovered after crash: 3 lines") ||
+		!strings.Contains(lines[1], "line 0") || !strings.Contains(lines[3], "line 2") || !strings.Contains(lines[4], "after restart") {
+		t.Fatalf("unexpected content %q", content)
+	}
+}
+
+func newBenchFileWriter(b *testing.B) *FileWriter {
+	fileWriter := newTestFileWriter(b, &FileWriterConf{
+		BufChanLen:    100000,
+		BufFullPolicy: BufFullPolicyBlock,
+	})
+	go fileWriter.Loop()
+	b.Cleanup(func() {
+		if err := fileWriter.Close(); err != nil {
+			b.Fatal(err)
+		}
+	})
+	return fileWriter
+}
+
+func BenchmarkFileWriterWrite(b *testing.B) {
+	fileWriter := newBenchFileWriter(b)
+	args := []interface{}{"order %s paid %d", "A1", 100}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := fileWriter.Write(logger.LevelInfo, args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoggerInfo(b *testing.B) {
+	fileLogger := logger.NewLogger(newBenchFileWriter(b)).With(logger.Int("uid", 10))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fileLogger.Info("order paid")
+	}
+}
@@ -0,0 +1,25 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+func TestStdLog(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{BufChanLen: 10})
+
+	stdLog := logger.NewStdLog(logger.NewLogger(fileWriter).With(logger.String("src", "std")), logger.LevelWarn)
+	stdLog.Printf("first\nsecond")
+	if fileWriter.queue.len() != 2 {
+		t.Fatalf("expect 2 lines, got %d", fileWriter.queue.len())
+	}
+	for _, msg := range []string{"first", "second"} {
+		line := popLine(fileWriter)
+		if !strings.Contains(line, "WARN github.com/995933447/log-go/v2/loggo/logger/writer.TestStdLog:line_writer_test.go") ||
+			!strings.HasSuffix(line, " "+msg+" src=std\n") {
+			t.Fatalf("unexpected line: %s", line)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package writer
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// fileWriterMetrics FileWriter自身运行状况的累计计数
+type fileWriterMetrics struct {
+	acceptedLines atomic.Int64
+	acceptedBytes atomic.Int64
+	droppedLines  atomic.Int64
+	maxQueueBytes atomic.Int64
+	writeCount    atomic.Int64
+	writeNanos    atomic.Int64
+	maxWriteNanos atomic.Int64
+	syncCount     atomic.Int64
+	syncNanos     atomic.Int64
+	maxSyncNanos  atomic.Int64
+	rotationCount atomic.Int64
+	errCount      atomic.Int64
+}
+
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		old := v.Load()
+		if n <= old || v.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+func (m *fileWriterMetrics) accept(bytes int, queueBytes int) {
+	m.acceptedLines.Add(1)
+	m.acceptedBytes.Add(int64(bytes))
+	storeMax(&m.maxQueueBytes, int64(queueBytes))
+}
+
+func (m *fileWriterMetrics) observeWrite(cost time.Duration) {
+	m.writeCount.Add(1)
+	m.writeNanos.Add(int64(cost))
+	storeMax(&m.maxWriteNanos, int64(cost))
+}
+
+func (m *fileWriterMetrics) observeSync(cost time.Duration) {
+	m.syncCount.Add(1)
+	m.syncNanos.Add(int64(cost))
+	storeMax(&m.maxSyncNanos, int64(cost))
+}
+
+// FileWriterStats FileWriter运行状况快照,除Queue开头的当前值外都是启动以来的累计值
+type FileWriterStats struct {
+	Name            string
+	AcceptedLines   int64 // 进入缓冲区或溢出文件的日志行数
+	AcceptedBytes   int64
+	DroppedLines    int64
+	QueueLen        int // 当前缓冲区排队行数
+	QueueBytes      int // 当前缓冲区占用字节数
+	QueueCapBytes   int
+	MaxQueueBytes   int64 // 缓冲区占用字节数的最大值
+	WriteCount      int64 // 写文件次数
+	WriteLatency    time.Duration
+	MaxWriteLatency time.Duration
+	SyncCount       int64 // fsync次数
+	SyncLatency     time.Duration
+	MaxSyncLatency  time.Duration
+	RotationCount   int64 // 切换新文件次数
+	ErrCount        int64
+}
+
+// Stats 获取当前运行状况快照
+func (w *FileWriter) Stats() FileWriterStats {
+	return FileWriterStats{
+		Name:            w.GetName(),
+		AcceptedLines:   w.metrics.acceptedLines.Load(),
+		AcceptedBytes:   w.metrics.acceptedBytes.Load(),
+		DroppedLines:    w.metrics.droppedLines.Load(),
+		QueueLen:        w.queue.len(),
+		QueueBytes:      w.queue.usedBytes(),
+		QueueCapBytes:   w.queue.capBytes(),
+		MaxQueueBytes:   w.metrics.maxQueueBytes.Load(),
+		WriteCount:      w.metrics.writeCount.Load(),
+		WriteLatency:    time.Duration(w.metrics.writeNanos.Load()),
+		MaxWriteLatency: time.Duration(w.metrics.maxWriteNanos.Load()),
+		SyncCount:       w.metrics.syncCount.Load(),
+		SyncLatency:     time.Duration(w.metrics.syncNanos.Load()),
+		MaxSyncLatency:  time.Duration(w.metrics.maxSyncNanos.Load()),
+		RotationCount:   w.metrics.rotationCount.Load(),
+		ErrCount:        w.metrics.errCount.Load(),
+	}
+}
+
+const metricsNamePrefix = "loggo_file_writer_"
+
+type metricDesc struct {
+	name      string
+	typ       string
+	help      string
+	getValues func(stats *FileWriterStats) []metricValue
+}
+
+type metricValue struct {
+	suffix string
+	value  float64
+}
+
+func singleValue(get func(stats *FileWriterStats) float64) func(stats *FileWriterStats) []metricValue {
+	return func(stats *FileWriterStats) []metricValue {
+		return []metricValue{{value: get(stats)}}
+	}
+}
+
+var metricDescs = []metricDesc{
+	{"accepted_lines_total", "counter", "Lines accepted into the buffer or spill file.", singleValue(func(s *FileWriterStats) float64 { return float64(s.AcceptedLines) })},
+	{"accepted_bytes_total", "counter", "Bytes accepted into the buffer or spill file.", singleValue(func(s *FileWriterStats) float64 { return float64(s.AcceptedBytes) })},
+	{"dropped_lines_total", "counter", "Lines dropped because the buffer was full.", singleValue(func(s *FileWriterStats) float64 { return float64(s.DroppedLines) })},
+	{"queue_length", "gauge", "Current number of lines queued in the buffer.", singleValue(func(s *FileWriterStats) float64 { return float64(s.QueueLen) })},
+	{"queue_bytes", "gauge", "Current number of bytes queued in the buffer.", singleValue(func(s *FileWriterStats) float64 { return float64(s.QueueBytes) })},
+	{"queue_capacity_bytes", "gauge", "Capacity of the buffer in bytes.", singleValue(func(s *FileWriterStats) float64 { return float64(s.QueueCapBytes) })},
+	{"queue_max_bytes", "gauge", "Maximum number of bytes ever queued in the buffer.", singleValue(func(s *FileWriterStats) float64 { return float64(s.MaxQueueBytes) })},
+	{"write_duration_seconds", "summary", "Latency of writes to the log file.", func(s *FileWriterStats) []metricValue {
+		return []metricValue{{"_sum", s.WriteLatency.Seconds()}, {"_count", float64(s.WriteCount)}}
+	}},
+	{"write_duration_max_seconds", "gauge", "Maximum latency of a write to the log file.", singleValue(func(s *FileWriterStats) float64 { return s.MaxWriteLatency.Seconds() })},
+	{"fsync_duration_seconds", "summary", "Latency of fsync on the log file.", func(s *FileWriterStats) []metricValue {
+		return []metricValue{{"_sum", s.SyncLatency.Seconds()}, {"_count", float64(s.SyncCount)}}
+	}},
+	{"fsync_duration_max_seconds", "gauge", "Maximum latency of an fsync on the log file.", singleValue(func(s *FileWriterStats) float64 { return s.MaxSyncLatency.Seconds() })},
+	{"rotations_total", "counter", "Number of times a new log file was opened.", singleValue(func(s *FileWriterStats) float64 { return float64(s.RotationCount) })},
+	{"errors_total", "counter", "Errors while writing, flushing or rotating.", singleValue(func(s *FileWriterStats) float64 { return float64(s.ErrCount) })},
+}
+
+// AppendPrometheusText 以Prometheus文本格式输出指标,每个writer以writer标签区分
+func AppendPrometheusText(b []byte, writers ...*FileWriter) []byte {
+	stats := make([]FileWriterStats, 0, len(writers))
+	for _, w := range writers {
+		stats = append(stats, w.Stats())
+	}
+
+	for _, desc := range metricDescs {
+		b = append(b, "# HELP "+metricsNamePrefix+desc.name+" "+desc.help+"\n"...)
+		b = append(b, "# TYPE "+metricsNamePrefix+desc.name+" "+desc.typ+"\n"...)
+		for i := range stats {
+			for _, v := range desc.getValues(&stats[i]) {
+				b = append(b, metricsNamePrefix+desc.name+v.suffix+`{writer="`...)
+				b = append(b, escapePrometheusLabel(stats[i].Name)...)
+				b = append(b, "\"} "...)
+				b = strconv.AppendFloat(b, v.value, 'g', -1, 64)
+				b = append(b, '\n')
+			}
+		}
+	}
+
+	return b
+}
+
+var prometheusLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapePrometheusLabel(s string) string {
+	return prometheusLabelReplacer.Replace(s)
+}
+
+// NewMetricsHandler 返回以Prometheus文本格式暴露writers指标的http.Handler
+func NewMetricsHandler(writers ...*FileWriter) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		resp.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = resp.Write(AppendPrometheusText(nil, writers...))
+	})
+}
@@ -0,0 +1,48 @@
+package writer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+func TestStats(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		FilePrefix:   "stats",
+		BufSizeBytes: 512,
+	})
+	for i := 0; i < 5; i++ {
+		if err := fileWriter.Write(logger.LevelInfo, "line %d %s", i, linePadding); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := fileWriter.Stats()
+	if stats.AcceptedLines != 2 || stats.DroppedLines != 3 || stats.QueueLen != 2 || stats.MaxQueueBytes != int64(stats.QueueBytes) || stats.AcceptedBytes == 0 {
+		t.Fatalf("unexpected stats before close: %+v", stats)
+	}
+
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stats = fileWriter.Stats()
+	if stats.QueueLen != 0 || stats.QueueBytes != 0 || stats.WriteCount == 0 || stats.SyncCount != 1 || stats.ErrCount != 0 {
+		t.Fatalf("unexpected stats after close: %+v", stats)
+	}
+
+	recorder := httptest.NewRecorder()
+	NewMetricsHandler(fileWriter).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, expect := range []string{
+		"# TYPE loggo_file_writer_dropped_lines_total counter\n",
+		`loggo_file_writer_dropped_lines_total{writer="stats"} 3` + "\n",
+		`loggo_file_writer_accepted_lines_total{writer="stats"} 2` + "\n",
+		`loggo_file_writer_fsync_duration_seconds_count{writer="stats"} 1` + "\n",
+	} {
+		if !strings.Contains(recorder.Body.String(), expect) {
+			t.Fatalf("metrics missing %q:\n%s", expect, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package writer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type logFileInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// removeFilesOverTotalBytes 日志文件总大小超过maxTotalBytes时从最早的文件开始删除,当前正在写的文件不删除
+func (w *FileWriter) removeFilesOverTotalBytes(maxTotalBytes int64) {
+	var (
+		files      []logFileInfo
+		totalBytes int64
+	)
+	_ = filepath.Walk(w.cfg.BaseDir, func(path string, info os.FileInfo, err error) error {
+		if info == nil || info.IsDir() || !w.isLogFile(path) {
+			return nil
+		}
+		totalBytes += info.Size()
+		if filepath.Base(path) != w.GetCurFileName() {
+			files = append(files, logFileInfo{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+		return nil
+	})
+	if totalBytes <= maxTotalBytes {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].path < files[j].path
+	})
+	for _, file := range files {
+		if totalBytes <= maxTotalBytes {
+			break
+		}
+		if err := os.Remove(file.path); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		totalBytes -= file.size
+		w.writeNotice(w.makeRemovedFileNotice(file, maxTotalBytes))
+	}
+}
+
+func (w *FileWriter) makeRemovedFileNotice(file logFileInfo, maxTotalBytes int64) []byte {
+	return w.appendNotice(nil, "removed log file %s (%d bytes) because total size exceeds MaxTotalBytes %d", file.path, file.size, maxTotalBytes)
+}
+
+// writeNotice 把日志文件自身的维护信息写进当前日志,缓冲区满时打印到标准输出
+func (w *FileWriter) writeNotice(notice []byte) {
+	w.pendingWrites.Add(1)
+	defer w.pendingWrites.Add(-1)
+
+	if w.closed.Load() || !w.queue.tryPush(notice) {
+		fmt.Print(string(notice))
+	}
+}
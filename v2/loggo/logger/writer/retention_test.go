@@ -0,0 +1,61 @@
+package writer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+func TestMaxTotalBytes(t *testing.T) {
+	baseDir := t.TempDir()
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		BaseDir:    baseDir,
+		FilePrefix: "app",
+		LogCfgLoader: newTestConfLoader(t, &logger.LogConf{
+			File: logger.FileLogConf{Level: "INFO", MaxTotalBytes: 250},
+		}),
+	})
+	var err error
+
+	// 当前文件最早但不能删除,其他前缀的文件不计入总大小
+	now := time.Now()
+	files := []struct {
+		name    string
+		size    int
+		modTime time.Time
+	}{
+		{"app.cur.txt", 100, now.Add(-4 * time.Hour)},
+		{"app.a.txt", 100, now.Add(-3 * time.Hour)},
+		{"app.b.txt.gz", 100, now.Add(-2 * time.Hour)},
+		{"app.c.txt", 100, now.Add(-time.Hour)},
+		{"other.txt", 1000, now.Add(-5 * time.Hour)},
+	}
+	for _, file := range files {
+		path := baseDir + "/" + file.name
+		if err = os.WriteFile(path, []byte(strings.Repeat("x", file.size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(path, file.modTime, file.modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fileWriter.curFileName.Store("app.cur.txt")
+
+	fileWriter.hdlExpiredFiles()
+
+	for _, file := range files {
+		_, err = os.Stat(baseDir + "/" + file.name)
+		removed := file.name == "app.a.txt" || file.name == "app.b.txt.gz"
+		if removed != os.IsNotExist(err) {
+			t.Fatalf("unexpected state of %s, removed:%v err:%v", file.name, removed, err)
+		}
+	}
+	for _, name := range []string{"app.a.txt", "app.b.txt.gz"} {
+		if line := popLine(fileWriter); !strings.Contains(line, "WARN ") || !strings.Contains(line, "removed log file "+baseDir+"/"+name) {
+			t.Fatalf("deletion of %s should be logged, got %q", name, line)
+		}
+	}
+}
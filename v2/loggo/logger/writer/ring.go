@@ -0,0 +1,288 @@
+package writer
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	ringHeaderBytes  = 4
+	ringAlignBytes   = 8
+	ringCommittedBit = 1 << 31
+	ringMinSizeBytes = 64
+)
+
+// byteRing 多生产者单消费者的字节环形缓冲.
+// 生产者CAS预留空间后先写入不带提交位的长度,拷入日志后再原子写入带提交位的记录头完成提交;
+// 消费者按顺序读取已提交的记录,释放时清零所占空间再推进tail,这样[tail, head)之外总是0.
+// 映射在文件上时读取后不立即释放,写进日志文件后由commitRead释放,崩溃时还没写入的记录仍在文件中.
+// 记录头按8字节对齐,不会跨越缓冲末尾,记录内容可以回绕.
+type byteRing struct {
+	buf  []byte
+	mask uint64
+
+	_    [64]byte
+	head atomic.Uint64 // 生产者预留到的位置
+	_    [56]byte
+	tail atomic.Uint64 // 消费者释放到的位置
+	_    [56]byte
+
+	readPos uint64 // 消费者读到的位置,没有映射文件时总是等于tail,需持有consumeMu
+
+	queuedLines atomic.Int64 // 已提交未消费的记录数
+
+	mapped     *ringMapping   // 不为空时buf映射在文件上,进程崩溃后可以恢复
+	mappedTail *atomic.Uint64 // 映射文件中的tail,只由消费者写入,释放前推进
+
+	// 消费端的锁,正常只有Loop使用,DropOldest策略下生产者也会借此丢弃最早的记录
+	consumeMu sync.Mutex
+
+	notifyCh chan struct{} // 有新记录提交时通知消费者
+
+	freedMu      sync.Mutex
+	freedCh      chan struct{} // 消费者释放空间时关闭,唤醒阻塞等待的生产者
+	freedWaiters atomic.Int32
+}
+
+func newByteRing(sizeBytes uint64) *byteRing {
+	return newByteRingOn(make([]byte, roundRingSize(sizeBytes)))
+}
+
+// newByteRingOn buf的长度必须是2的幂
+func newByteRingOn(buf []byte) *byteRing {
+	return &byteRing{
+		buf:      buf,
+		mask:     uint64(len(buf)) - 1,
+		notifyCh: make(chan struct{}, 1),
+		freedCh:  make(chan struct{}),
+	}
+}
+
+func roundRingSize(sizeBytes uint64) uint64 {
+	size := uint64(ringMinSizeBytes)
+	for size < sizeBytes {
+		size <<= 1
+	}
+	return size
+}
+
+func ringRecordBytes(n int) uint64 {
+	return (uint64(ringHeaderBytes+n) + ringAlignBytes - 1) &^ (ringAlignBytes - 1)
+}
+
+func (r *byteRing) header(pos uint64) *uint32 {
+	return (*uint32)(unsafe.Pointer(&r.buf[pos&r.mask]))
+}
+
+// copyIn 从pos开始写入p,到达末尾时回绕
+func (r *byteRing) copyIn(pos uint64, p []byte) {
+	n := copy(r.buf[pos&r.mask:], p)
+	copy(r.buf, p[n:])
+}
+
+// appendOut 从pos开始读取n字节追加到dst,到达末尾时回绕
+func (r *byteRing) appendOut(dst []byte, pos uint64, n int) []byte {
+	start := pos & r.mask
+	if end := start + uint64(n); end <= uint64(len(r.buf)) {
+		return append(dst, r.buf[start:end]...)
+	}
+	dst = append(dst, r.buf[start:]...)
+	return append(dst, r.buf[:uint64(n)-(uint64(len(r.buf))-start)]...)
+}
+
+// clearRange 清零[pos, pos+n),到达末尾时回绕
+func (r *byteRing) clearRange(pos, n uint64) {
+	start := pos & r.mask
+	if end := start + n; end <= uint64(len(r.buf)) {
+		clear(r.buf[start:end])
+		return
+	}
+	clear(r.buf[start:])
+	clear(r.buf[:n-(uint64(len(r.buf))-start)])
+}
+
+// canFit 超过缓冲大小的记录永远写不进
+func (r *byteRing) canFit(n int) bool {
+	return n < ringCommittedBit && ringRecordBytes(n) <= uint64(len(r.buf))
+}
+
+// tryPush 空间不足时返回false
+func (r *byteRing) tryPush(p []byte) bool {
+	if !r.canFit(len(p)) {
+		return false
+	}
+	need := ringRecordBytes(len(p))
+
+	var pos uint64
+	for {
+		// 先读tail,保证tail不大于随后读到的head
+		tail := r.tail.Load()
+		pos = r.head.Load()
+		if pos+need-tail > uint64(len(r.buf)) {
+			return false
+		}
+		if r.head.CompareAndSwap(pos, pos+need) {
+			break
+		}
+	}
+
+	// 先写入长度,崩溃恢复时可以跳过没有提交完的记录
+	atomic.StoreUint32(r.header(pos), uint32(len(p)))
+	r.copyIn(pos+ringHeaderBytes, p)
+	atomic.StoreUint32(r.header(pos), uint32(len(p))|ringCommittedBit)
+	r.queuedLines.Add(1)
+
+	select {
+	case r.notifyCh <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// peekLocked 最早一条未读取的已提交记录的位置和长度,调用方需持有consumeMu
+func (r *byteRing) peekLocked() (uint64, int, bool) {
+	pos := r.readPos
+	if pos == r.head.Load() {
+		return 0, 0, false
+	}
+
+	hdr := atomic.LoadUint32(r.header(pos))
+	if hdr&ringCommittedBit == 0 {
+		// 生产者已预留还未提交
+		return 0, 0, false
+	}
+
+	return pos, int(hdr &^ ringCommittedBit), true
+}
+
+// readLocked 读取记录后推进readPos,没有映射文件时立即释放,调用方需持有consumeMu
+func (r *byteRing) readLocked(pos uint64, n int) {
+	r.readPos = pos + ringRecordBytes(n)
+	r.queuedLines.Add(-1)
+	if r.mapped == nil {
+		r.releaseLocked()
+	}
+}
+
+// releaseLocked 清零[tail, readPos)后推进tail,返回是否释放了空间,调用方需持有consumeMu
+func (r *byteRing) releaseLocked() bool {
+	tail := r.tail.Load()
+	if tail == r.readPos {
+		return false
+	}
+	// 映射文件中的tail先推进,清零中途崩溃时恢复不会读到清零了一半的记录
+	if r.mappedTail != nil {
+		r.mappedTail.Store(r.readPos)
+	}
+	r.clearRange(tail, r.readPos-tail)
+	r.tail.Store(r.readPos)
+	return true
+}
+
+// commitRead 已读取的记录写进日志文件后调用,释放其空间.没有映射文件时读取就已释放
+func (r *byteRing) commitRead() {
+	if r.mapped == nil {
+		return
+	}
+
+	r.consumeMu.Lock()
+	freed := r.releaseLocked()
+	r.consumeMu.Unlock()
+
+	if freed {
+		r.notifyFreed()
+	}
+}
+
+// pop 取出最早的一条记录追加到dst
+func (r *byteRing) pop(dst []byte) ([]byte, bool) {
+	r.consumeMu.Lock()
+	pos, n, ok := r.peekLocked()
+	if ok {
+		dst = r.appendOut(dst, pos+ringHeaderBytes, n)
+		r.readLocked(pos, n)
+	}
+	r.consumeMu.Unlock()
+
+	if ok && r.mapped == nil {
+		r.notifyFreed()
+	}
+	return dst, ok
+}
+
+// drain 按顺序取出已提交的记录追加到dst,追加的字节数超过maxBytes后停止.
+// 映射文件时需要在写入日志文件后调用commitRead
+func (r *byteRing) drain(dst []byte, maxBytes int) []byte {
+	r.consumeMu.Lock()
+	var (
+		start = len(dst)
+		freed bool
+	)
+	for len(dst)-start < maxBytes {
+		pos, n, ok := r.peekLocked()
+		if !ok {
+			break
+		}
+		dst = r.appendOut(dst, pos+ringHeaderBytes, n)
+		r.readLocked(pos, n)
+		freed = r.mapped == nil
+	}
+	r.consumeMu.Unlock()
+
+	if freed {
+		r.notifyFreed()
+	}
+	return dst
+}
+
+// dropOldest 丢弃最早的一条记录腾出空间.
+// 映射文件时已读取还没写入的记录占着最早的空间,丢弃后面的记录腾不出空间,返回false
+func (r *byteRing) dropOldest() bool {
+	r.consumeMu.Lock()
+	pos, n, ok := r.peekLocked()
+	if ok && pos != r.tail.Load() {
+		ok = false
+	}
+	if ok {
+		r.readLocked(pos, n)
+		r.releaseLocked()
+	}
+	r.consumeMu.Unlock()
+
+	if ok {
+		r.notifyFreed()
+	}
+	return ok
+}
+
+// waitFreed 返回下一次释放空间时会关闭的chan,需要在重试写入之前获取,避免错过唤醒
+func (r *byteRing) waitFreed() <-chan struct{} {
+	r.freedMu.Lock()
+	defer r.freedMu.Unlock()
+	return r.freedCh
+}
+
+func (r *byteRing) notifyFreed() {
+	if r.freedWaiters.Load() == 0 {
+		return
+	}
+	r.freedMu.Lock()
+	close(r.freedCh)
+	r.freedCh = make(chan struct{})
+	r.freedMu.Unlock()
+}
+
+func (r *byteRing) len() int {
+	// 提交和计数之间记录可能已经被消费,计数会短暂为负
+	return max(int(r.queuedLines.Load()), 0)
+}
+
+func (r *byteRing) usedBytes() int {
+	return int(r.head.Load() - r.tail.Load())
+}
+
+func (r *byteRing) capBytes() int {
+	return len(r.buf)
+}
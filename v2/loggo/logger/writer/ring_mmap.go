@@ -0,0 +1,142 @@
+package writer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"unsafe"
+)
+
+// 映射文件的布局: magic(8) | 缓冲大小(8) | tail(8) | 补齐到64字节 | 缓冲
+const (
+	ringFileMagic       = "LOGGORB1"
+	ringFileMetaBytes   = 64
+	ringFileSizeOffset  = 8
+	ringFileTailOffset  = 16
+	ringFileDefaultName = "buf.mmap"
+)
+
+var (
+	// ErrMmapNotSupported 当前平台不支持映射文件的缓冲区
+	ErrMmapNotSupported = errors.New("mmap buffer is not supported on this platform")
+	// ErrBadRingFile 映射文件不是日志缓冲区或者已经损坏
+	ErrBadRingFile = errors.New("bad ring buffer file")
+	// ErrRingFileLocked 映射文件正在被其他进程使用
+	ErrRingFileLocked = errors.New("ring buffer file is locked by another process")
+)
+
+type ringMapping struct {
+	fp  *os.File
+	mem []byte
+}
+
+// newMappedByteRing 把缓冲区映射在path文件上,上次进程没有写进日志文件的记录交给onRecovered,
+// onRecovered返回错误时不重置文件,下次启动还能恢复.
+// 文件加了排他锁,其他进程正在使用时返回ErrRingFileLocked,避免把别人还没写的记录当作崩溃遗留.
+// 生产者的开销与内存中的缓冲区一样只有一次拷贝,被杀掉后内核仍会把映射的内容写回文件
+func newMappedByteRing(path string, sizeBytes uint64, onRecovered func(recovered []byte, lines int) error) (*byteRing, error) {
+	fp, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// 锁随文件关闭释放,进程退出或崩溃后不会残留
+	if err = lockFile(fp); err != nil {
+		_ = fp.Close()
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+
+	recovered, recoveredLines, err := recoverRingFile(fp, path)
+	if err == nil && recoveredLines > 0 {
+		err = onRecovered(recovered, recoveredLines)
+	}
+	if err != nil {
+		_ = fp.Close()
+		return nil, err
+	}
+
+	// 截断后重新扩展,缓冲区全部为0
+	size := roundRingSize(sizeBytes)
+	if err = fp.Truncate(0); err == nil {
+		err = fp.Truncate(int64(ringFileMetaBytes + size))
+	}
+	if err != nil {
+		_ = fp.Close()
+		return nil, err
+	}
+
+	mem, err := mmapFile(fp, int(ringFileMetaBytes+size))
+	if err != nil {
+		_ = fp.Close()
+		return nil, err
+	}
+	copy(mem, ringFileMagic)
+	binary.NativeEndian.PutUint64(mem[ringFileSizeOffset:], size)
+
+	r := newByteRingOn(mem[ringFileMetaBytes:])
+	r.mapped = &ringMapping{fp: fp, mem: mem}
+	r.mappedTail = (*atomic.Uint64)(unsafe.Pointer(&mem[ringFileTailOffset]))
+
+	return r, nil
+}
+
+// recoverRingFile 从持久化的tail开始扫描整个缓冲,按顺序取出已提交的记录.
+// [tail, head)之外全是0,生产者预留后还没写入长度的记录也是全0,都按对齐长度跳过,不影响后面已提交的记录
+func recoverRingFile(fp *os.File, path string) ([]byte, int, error) {
+	content, err := io.ReadAll(fp)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(content) == 0 {
+		return nil, 0, nil
+	}
+
+	if len(content) < ringFileMetaBytes || string(content[:len(ringFileMagic)]) != ringFileMagic {
+		return nil, 0, fmt.Errorf("%w: %s", ErrBadRingFile, path)
+	}
+	size := binary.NativeEndian.Uint64(content[ringFileSizeOffset:])
+	if size < ringMinSizeBytes || size&(size-1) != 0 || uint64(len(content)) != ringFileMetaBytes+size {
+		return nil, 0, fmt.Errorf("%w: %s", ErrBadRingFile, path)
+	}
+
+	var (
+		buf       = content[ringFileMetaBytes:]
+		r         = &byteRing{buf: buf, mask: size - 1}
+		pos       = binary.NativeEndian.Uint64(content[ringFileTailOffset:])
+		scanned   uint64
+		recovered []byte
+		lines     int
+	)
+	for scanned < size {
+		hdr := binary.NativeEndian.Uint32(buf[pos&r.mask:])
+		if hdr == 0 {
+			pos += ringAlignBytes
+			scanned += ringAlignBytes
+			continue
+		}
+		n := int(hdr &^ ringCommittedBit)
+		recordBytes := ringRecordBytes(n)
+		if recordBytes > size-scanned {
+			break
+		}
+		// 没有提交的记录内容不完整,跳过
+		if hdr&ringCommittedBit != 0 {
+			recovered = r.appendOut(recovered, pos+ringHeaderBytes, n)
+			lines++
+		}
+		pos += recordBytes
+		scanned += recordBytes
+	}
+
+	return recovered, lines, nil
+}
+
+// close 解除映射,调用方需保证不再有读写
+func (r *byteRing) close() error {
+	if r.mapped == nil {
+		return nil
+	}
+	return errors.Join(munmapFile(r.mapped.mem), r.mapped.fp.Close())
+}
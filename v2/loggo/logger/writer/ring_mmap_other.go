@@ -0,0 +1,17 @@
+//go:build !unix || aix || (solaris && !illumos)
+
+package writer
+
+import "os"
+
+func mmapFile(fp *os.File, size int) ([]byte, error) {
+	return nil, ErrMmapNotSupported
+}
+
+func munmapFile(mem []byte) error {
+	return ErrMmapNotSupported
+}
+
+func lockFile(fp *os.File) error {
+	return ErrMmapNotSupported
+}
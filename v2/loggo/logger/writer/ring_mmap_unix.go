@@ -0,0 +1,25 @@
+//go:build unix && !aix && !(solaris && !illumos)
+
+package writer
+
+import (
+	"os"
+	"syscall"
+)
+
+func mmapFile(fp *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(fp.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func munmapFile(mem []byte) error {
+	return syscall.Munmap(mem)
+}
+
+// lockFile 加非阻塞的排他锁
+func lockFile(fp *os.File) error {
+	err := syscall.Flock(int(fp.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return ErrRingFileLocked
+	}
+	return err
+}
@@ -0,0 +1,284 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestByteRing(t *testing.T) {
+	ring := newByteRing(100)
+	if ring.capBytes() != 128 {
+		t.Fatalf("expect size rounded up to 128, got %d", ring.capBytes())
+	}
+	if ring.canFit(125) {
+		t.Fatal("record larger than ring should not fit")
+	}
+
+	// 反复写读让记录跨越缓冲末尾
+	for i := 0; i < 100; i++ {
+		line := []byte(fmt.Sprintf("line %d %s\n", i, bytes.Repeat([]byte{'x'}, i%40)))
+		if !ring.tryPush(line) {
+			t.Fatalf("push %d failed", i)
+		}
+		got, ok := ring.pop(nil)
+		if !ok || !bytes.Equal(got, line) {
+			t.Fatalf("expect %q, got %q", line, got)
+		}
+	}
+
+	for ring.tryPush([]byte("abcdefghijk")) {
+	}
+	if !ring.dropOldest() || !ring.tryPush([]byte("abcdefghijk")) {
+		t.Fatal("push should succeed after dropping oldest")
+	}
+}
+
+func TestByteRingConcurrent(t *testing.T) {
+	const (
+		producers = 8
+		perWorker = 20000
+	)
+	ring := newByteRing(4096)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				line := []byte(strconv.Itoa(p) + " " + strconv.Itoa(i) + "\n")
+				for !ring.tryPush(line) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	// 每个生产者的日志必须完整且按顺序
+	var (
+		next  [producers]int
+		total int
+		batch []byte
+	)
+	for total < producers*perWorker {
+		batch = ring.drain(batch[:0], batchWriteBytes)
+		if len(batch) == 0 {
+			select {
+			case <-ring.notifyCh:
+			case <-doneCh:
+			}
+			continue
+		}
+		for _, line := range bytes.Split(bytes.TrimSuffix(batch, []byte{'\n'}), []byte{'\n'}) {
+			parts := bytes.Split(line, []byte{' '})
+			p, _ := strconv.Atoi(string(parts[0]))
+			i, _ := strconv.Atoi(string(parts[1]))
+			if len(parts) != 2 || p >= producers || i != next[p] {
+				t.Fatalf("unexpected line %q, expect %d %d", line, p, next[p])
+			}
+			next[p]++
+			total++
+		}
+	}
+	if ring.len() != 0 || ring.usedBytes() != 0 {
+		t.Fatalf("ring should be empty, len:%d bytes:%d", ring.len(), ring.usedBytes())
+	}
+}
+
+func TestMappedByteRingRecover(t *testing.T) {
+	path := t.TempDir() + "/app.buf.mmap"
+	var (
+		recovered string
+		lines     int
+	)
+	onRecovered := func(b []byte, n int) error {
+		recovered, lines = string(b), n
+		return nil
+	}
+	ring, err := newMappedByteRing(path, 100, onRecovered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered != "" || lines != 0 {
+		t.Fatalf("new file should recover nothing, got %d lines", lines)
+	}
+
+	// 写满后回绕,保证恢复时跨越缓冲末尾
+	for i := 0; i < 10; i++ {
+		if !ring.tryPush([]byte(fmt.Sprintf("line %d\n", i))) {
+			t.Fatalf("push %d failed", i)
+		}
+		if i < 8 {
+			ring.pop(nil)
+			ring.commitRead()
+		}
+	}
+	// 模拟写入了长度还没提交时进程被杀掉
+	pos := ring.head.Load()
+	ring.head.Store(pos + ringRecordBytes(8))
+	*ring.header(pos) = 8
+	ring.copyIn(pos+ringHeaderBytes, []byte("partial\n"))
+	// 模拟预留了空间还没写入长度时进程被杀掉
+	ring.head.Add(ringRecordBytes(8))
+	if !ring.tryPush([]byte("line 10\n")) {
+		t.Fatal("push after partial record failed")
+	}
+	// 已经读取还没写进日志文件的记录也要恢复
+	if batch := ring.drain(nil, 1); string(batch) != "line 8\n" {
+		t.Fatalf("unexpected batch %q", batch)
+	}
+
+	// 其他进程正在使用时不能恢复和重置
+	if _, err = newMappedByteRing(path, 100, onRecovered); !errors.Is(err, ErrRingFileLocked) || lines != 0 {
+		t.Fatalf("expect ErrRingFileLocked, got %v, recovered %d lines", err, lines)
+	}
+	// 只解除映射不改动文件,相当于进程崩溃
+	if err = ring.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 恢复的日志没有处理成功时不重置文件
+	if _, err = newMappedByteRing(path, 100, func([]byte, int) error { return os.ErrPermission }); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("expect error from onRecovered, got %v", err)
+	}
+
+	newRing, err := newMappedByteRing(path, 100, onRecovered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered != "line 8\nline 9\nline 10\n" || lines != 3 {
+		t.Fatalf("unexpected recovered %q, lines:%d", recovered, lines)
+	}
+
+	// 正常消费完后关闭不会重复恢复
+	if !newRing.tryPush([]byte("line 11\n")) {
+		t.Fatal("push to new ring failed")
+	}
+	newRing.pop(nil)
+	newRing.commitRead()
+	if err = newRing.close(); err != nil {
+		t.Fatal(err)
+	}
+	recovered, lines = "", 0
+	if newRing, err = newMappedByteRing(path, 100, onRecovered); err != nil || lines != 0 {
+		t.Fatalf("should recover nothing after consumed, got %q %v", recovered, err)
+	}
+	if err = newRing.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(path, []byte("not a ring"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = newMappedByteRing(path, 100, onRecovered); !errors.Is(err, ErrBadRingFile) {
+		t.Fatalf("expect ErrBadRingFile, got %v", err)
+	}
+}
+
+var benchLine = bytes.Repeat([]byte{'x'}, 200)
+
+// benchmarkQueue producers个协程共写入b.N行,消费者按Loop的方式合并成批
+func benchmarkQueue(b *testing.B, producers int, push func(line []byte) bool, consume func(batch []byte) []byte) {
+	var (
+		wg     sync.WaitGroup
+		doneCh = make(chan struct{})
+	)
+	go func() {
+		var batch []byte
+		for {
+			select {
+			case <-doneCh:
+				return
+			default:
+			}
+			if batch = consume(batch[:0]); len(batch) == 0 {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	b.SetBytes(int64(len(benchLine)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for p := 0; p < producers; p++ {
+		n := b.N / producers
+		if p < b.N%producers {
+			n++
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				for !push(benchLine) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	b.StopTimer()
+	close(doneCh)
+}
+
+func BenchmarkQueue(b *testing.B) {
+	for _, producers := range []int{1, 8, 64} {
+		// 替换前的实现:每行日志放在池化的buffer中经chan传给消费者
+		b.Run(fmt.Sprintf("chan/producers=%d", producers), func(b *testing.B) {
+			ch := make(chan *buffer, defaultBufSizeBytes/estimatedLineBytes)
+			benchmarkQueue(b, producers, func(line []byte) bool {
+				buf := getBuffer()
+				buf.b = append(buf.b, line...)
+				select {
+				case ch <- buf:
+					return true
+				default:
+					buf.free()
+					return false
+				}
+			}, func(batch []byte) []byte {
+				for len(batch) <= batchWriteBytes {
+					var buf *buffer
+					select {
+					case buf = <-ch:
+					default:
+					}
+					if buf == nil {
+						break
+					}
+					batch = append(batch, buf.b...)
+					buf.free()
+				}
+				return batch
+			})
+		})
+		b.Run(fmt.Sprintf("ring/producers=%d", producers), func(b *testing.B) {
+			ring := newByteRing(defaultBufSizeBytes)
+			benchmarkQueue(b, producers, ring.tryPush, func(batch []byte) []byte {
+				return ring.drain(batch, batchWriteBytes)
+			})
+		})
+		b.Run(fmt.Sprintf("mmap/producers=%d", producers), func(b *testing.B) {
+			ring, err := newMappedByteRing(b.TempDir()+"/bench.buf.mmap", defaultBufSizeBytes, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer ring.close()
+			benchmarkQueue(b, producers, ring.tryPush, func(batch []byte) []byte {
+				batch = ring.drain(batch, batchWriteBytes)
+				ring.commitRead()
+				return batch
+			})
+		})
+	}
+}
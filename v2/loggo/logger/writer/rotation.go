@@ -0,0 +1,201 @@
+package writer
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+const (
+	HourlyRotationLayout = "2006010215"
+	DailyRotationLayout  = "20060102"
+
+	rotationStateFileSuffix = "rotation.state"
+	segmentIndexMinDigits   = 3
+)
+
+// RotationPolicy 决定何时切换日志文件以及文件名.
+// 周期变化时切换到新周期的第1个分段,当前分段达到大小上限时切换到同一周期的下一个分段
+type RotationPolicy interface {
+	// Period now所属的时间周期,不按时间切换时返回空字符串
+	Period(now time.Time) string
+	// MaxSizeBytes 单个分段的最大字节数,<=0时不按大小切换
+	MaxSizeBytes() int64
+	// FileName 周期内第segment个分段的文件名,segment从1开始
+	FileName(prefix, period string, segment int) string
+}
+
+var _ RotationPolicy = (*TimeSizeRotation)(nil)
+
+// TimeSizeRotation 按时间周期和分段大小切换,文件名为 前缀+周期_标识_分段序号.txt
+type TimeSizeRotation struct {
+	Layout    string             // 时间周期的格式,为空时不按时间切换
+	MaxBytes  int64              // 单个分段的最大字节数,<=0时不按大小切换
+	CfgLoader *logger.ConfLoader // 不为空且MaxBytes<=0时取配置中的File.MaxFileSizeBytes,支持热更新
+	NameTag   string             // 附加在周期和分段序号之间的标识,例如节点id
+}
+
+// NewHourlyRotation 每小时切换
+func NewHourlyRotation() *TimeSizeRotation {
+	return &TimeSizeRotation{Layout: HourlyRotationLayout}
+}
+
+// NewDailyRotation 每天切换
+func NewDailyRotation() *TimeSizeRotation {
+	return &TimeSizeRotation{Layout: DailyRotationLayout}
+}
+
+// NewSizeRotation 只按大小切换
+func NewSizeRotation(maxBytes int64) *TimeSizeRotation {
+	return &TimeSizeRotation{MaxBytes: maxBytes}
+}
+
+// NewTimeSizeRotation 按layout格式的时间周期切换,周期内按大小分段
+func NewTimeSizeRotation(layout string, maxBytes int64) *TimeSizeRotation {
+	return &TimeSizeRotation{Layout: layout, MaxBytes: maxBytes}
+}
+
+func (p *TimeSizeRotation) Period(now time.Time) string {
+	if p.Layout == "" {
+		return ""
+	}
+	return now.Format(p.Layout)
+}
+
+func (p *TimeSizeRotation) MaxSizeBytes() int64 {
+	if p.MaxBytes <= 0 && p.CfgLoader != nil {
+		return p.CfgLoader.GetConf().File.MaxFileSizeBytes
+	}
+	return p.MaxBytes
+}
+
+func (p *TimeSizeRotation) FileName(prefix, period string, segment int) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	if period != "" {
+		b.WriteString(period)
+		b.WriteByte('_')
+	}
+	if p.NameTag != "" {
+		b.WriteString(p.NameTag)
+		b.WriteByte('_')
+	}
+	segmentStr := strconv.Itoa(segment)
+	for i := len(segmentStr); i < segmentIndexMinDigits; i++ {
+		b.WriteByte('0')
+	}
+	b.WriteString(segmentStr)
+	b.WriteString(FileSuffix)
+	return b.String()
+}
+
+// rotationState 当前文件所属的周期和分段,持久化到文件,重启后接着上次的分段写
+type rotationState struct {
+	Period   string `json:"period"`
+	Segment  int    `json:"segment"`
+	FileName string `json:"file_name"`
+}
+
+func (w *FileWriter) getRotationStateFileName() string {
+	return w.cfg.BaseDir + "/" + w.getFilePrefix() + rotationStateFileSuffix
+}
+
+func (w *FileWriter) loadRotationState() (rotationState, error) {
+	var state rotationState
+	content, err := os.ReadFile(w.getRotationStateFileName())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+	if err = json.Unmarshal(content, &state); err != nil {
+		return rotationState{}, err
+	}
+	return state, nil
+}
+
+// saveRotationState 先写临时文件再rename,避免进程中途退出留下不完整的状态
+func (w *FileWriter) saveRotationState() error {
+	content, err := json.Marshal(&w.rotation)
+	if err != nil {
+		return err
+	}
+	stateFileName := w.getRotationStateFileName()
+	if err = os.WriteFile(stateFileName+".tmp", content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(stateFileName+".tmp", stateFileName)
+}
+
+// tryRotate 按RotationPolicy判断是否需要切换文件,每秒最多计算一次周期
+func (w *FileWriter) tryRotate() error {
+	now := time.Now()
+	policy := w.cfg.RotationPolicy
+
+	if w.fp != nil {
+		if now.Unix() != w.rotationCheckedAt {
+			w.rotationCheckedAt = now.Unix()
+			w.curPeriod = policy.Period(now)
+		}
+		isSegmentFull := policy.MaxSizeBytes() > 0 && w.curSizeBytes.Load() >= policy.MaxSizeBytes()
+		if w.curPeriod == w.rotation.Period && !isSegmentFull {
+			return nil
+		}
+	} else {
+		w.rotationCheckedAt = now.Unix()
+		w.curPeriod = policy.Period(now)
+	}
+
+	var (
+		next    rotationState
+		loadErr error
+	)
+	switch {
+	case w.fp != nil && w.curPeriod == w.rotation.Period:
+		next = rotationState{Period: w.curPeriod, Segment: w.rotation.Segment + 1}
+	case w.fp != nil:
+		next = rotationState{Period: w.curPeriod, Segment: 1}
+	default:
+		// 首次打开,上次的分段属于当前周期时接着写,写满了则从下一个分段开始
+		var last rotationState
+		last, loadErr = w.loadRotationState()
+		next = rotationState{Period: w.curPeriod, Segment: 1}
+		if last.FileName != "" && last.Period == w.curPeriod {
+			next = last
+			if fileInfo, err := os.Stat(w.cfg.BaseDir + "/" + last.FileName); err == nil && policy.MaxSizeBytes() > 0 && fileInfo.Size() >= policy.MaxSizeBytes() {
+				next = rotationState{Period: w.curPeriod, Segment: last.Segment + 1}
+			}
+		}
+	}
+
+	if next.FileName == "" {
+		// 新分段跳过已经存在的文件及其压缩文件,例如状态文件丢失时
+		for {
+			next.FileName = policy.FileName(w.getFilePrefix(), next.Period, next.Segment)
+			if !w.logFileExists(next.FileName) {
+				break
+			}
+			next.Segment++
+		}
+	}
+
+	// 只有打开文件失败时才无法写入,状态文件读写失败不影响本次写日志
+	if err := w.openFile(next.FileName); err != nil {
+		return err
+	}
+	w.rotation = next
+
+	if loadErr != nil {
+		w.hdlLogErr(loadErr)
+	}
+	if err := w.saveRotationState(); err != nil {
+		w.hdlLogErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package writer
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+func TestTimeSizeRotationFileName(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local)
+	hourly := &TimeSizeRotation{Layout: HourlyRotationLayout, NameTag: "7"}
+	if name := hourly.FileName("app.", hourly.Period(now), 2); name != "app.2025010215_7_002.txt" {
+		t.Fatalf("unexpected hourly name %s", name)
+	}
+	daily := NewDailyRotation()
+	if name := daily.FileName("app.", daily.Period(now), 12); name != "app.20250102_012.txt" {
+		t.Fatalf("unexpected daily name %s", name)
+	}
+	size := NewSizeRotation(100)
+	if name := size.FileName("", size.Period(now), 1000); name != "1000.txt" {
+		t.Fatalf("unexpected size name %s", name)
+	}
+}
+
+func TestRotationBySize(t *testing.T) {
+	baseDir := t.TempDir()
+	newWriter := func() *FileWriter {
+		return newTestFileWriter(t, &FileWriterConf{
+			BaseDir:        baseDir,
+			FilePrefix:     "app",
+			RotationPolicy: NewSizeRotation(300),
+		})
+	}
+	writeLines := func(fileWriter *FileWriter, n int) {
+		for i := 0; i < n; i++ {
+			if err := fileWriter.Write(logger.LevelInfo, "line %d %s", i, linePadding); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fileWriter.writeQueued(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// 每行200字节左右,每个分段写2行
+	fileWriter := newWriter()
+	writeLines(fileWriter, 5)
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fileWriter.GetCurFileName() != "app.003.txt" || fileWriter.Stats().RotationCount != 2 {
+		t.Fatalf("unexpected rotation, file:%s stats:%+v", fileWriter.GetCurFileName(), fileWriter.Stats())
+	}
+	if target, err := os.Readlink(baseDir + "/app.current.txt"); err != nil || target != "app.003.txt" {
+		t.Fatalf("current link should point to app.003.txt, got %s %v", target, err)
+	}
+
+	// 重启后接着写未满的分段,写满后切换到下一个分段
+	fileWriter = newWriter()
+	writeLines(fileWriter, 1)
+	if fileWriter.GetCurFileName() != "app.003.txt" {
+		t.Fatalf("should continue last segment, got %s", fileWriter.GetCurFileName())
+	}
+	writeLines(fileWriter, 1)
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fileWriter.GetCurFileName() != "app.004.txt" {
+		t.Fatalf("should rotate to next segment, got %s", fileWriter.GetCurFileName())
+	}
+
+	// 状态文件丢失时跳过已存在的分段
+	if err := os.Remove(fileWriter.getRotationStateFileName()); err != nil {
+		t.Fatal(err)
+	}
+	fileWriter = newWriter()
+	writeLines(fileWriter, 1)
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fileWriter.GetCurFileName() != "app.005.txt" {
+		t.Fatalf("should skip existing segments, got %s", fileWriter.GetCurFileName())
+	}
+}
+
+func TestRotationStateSaveFailed(t *testing.T) {
+	baseDir := t.TempDir()
+	var errCount int
+	fileWriter := newTestFileWriter(t, &FileWriterConf{
+		BaseDir:        baseDir,
+		FilePrefix:     "app",
+		RotationPolicy: NewSizeRotation(300),
+		OnLogErr: func(err error) {
+			errCount++
+		},
+	})
+	// 状态文件路径是目录,保存状态总是失败
+	if err := os.MkdirAll(fileWriter.getRotationStateFileName(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 6; i++ {
+		if err := fileWriter.Write(logger.LevelInfo, "line %d %s", i, linePadding); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fileWriter.writeQueued(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fileName := range []string{"app.001.txt", "app.002.txt", "app.003.txt"} {
+		content, err := os.ReadFile(baseDir + "/" + fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Count(string(content), "\n"); lines != 2 {
+			t.Fatalf("%s should have 2 lines, got %d", fileName, lines)
+		}
+	}
+	if errCount == 0 || fileWriter.Stats().ErrCount != int64(errCount) {
+		t.Fatalf("save errors should be reported, callback:%d stats:%+v", errCount, fileWriter.Stats())
+	}
+}
+
+func TestDefaultRotationKeepsLegacyName(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{FilePrefix: "app"})
+	if err := fileWriter.Write(logger.LevelInfo, "line"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileWriter.writeQueued(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if name := fileWriter.GetCurFileName(); !regexp.MustCompile(`^app\.\d{12}_\.txt$`).MatchString(name) {
+		t.Fatalf("default file name should be app.YYYYMMDDhhmm_.txt, got %s", name)
+	}
+}
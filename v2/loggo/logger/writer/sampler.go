@@ -0,0 +1,86 @@
+package writer
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+// reportInterval 采样和丢弃统计行的输出周期
+const reportInterval = time.Minute
+
+type sampleCounter struct {
+	intervalStartAt atomic.Int64
+	n               atomic.Uint64
+	dropped         atomic.Uint64
+	fileName        string
+	line            int
+}
+
+// sampler 按调用点pc采样,每个周期前SampleFirst行全部输出,之后每SampleThereafter行输出一行
+type sampler struct {
+	counters sync.Map // uintptr => *sampleCounter
+}
+
+// isSampleEnabled SampleFirst和SampleThereafter都为0时会丢弃全部日志,视为配置不完整不采样
+func isSampleEnabled(cfg *logger.FileLogConf) bool {
+	return cfg.SampleIntervalSec > 0 && cfg.SampleFirst >= 0 && (cfg.SampleFirst > 0 || cfg.SampleThereafter > 0)
+}
+
+func getSampleMaxLevel(cfg *logger.FileLogConf) logger.Level {
+	if cfg.SampleMaxLevel == "" {
+		return logger.LevelInfo
+	}
+	return cfg.GetSampleMaxLevel()
+}
+
+func (s *sampler) check(pc uintptr, cfg *logger.FileLogConf) bool {
+	counterAny, ok := s.counters.Load(pc)
+	if !ok {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		fileName := frame.File
+		if lastSlash := strings.LastIndexByte(fileName, '/'); lastSlash >= 0 {
+			fileName = fileName[lastSlash+1:]
+		}
+		counterAny, _ = s.counters.LoadOrStore(pc, &sampleCounter{fileName: fileName, line: frame.Line})
+	}
+	counter := counterAny.(*sampleCounter)
+
+	now := time.Now().UnixNano()
+	intervalStartAt := counter.intervalStartAt.Load()
+	if now-intervalStartAt >= int64(cfg.SampleIntervalSec)*int64(time.Second) {
+		if counter.intervalStartAt.CompareAndSwap(intervalStartAt, now) {
+			counter.n.Store(0)
+		}
+	}
+
+	n := counter.n.Add(1)
+	if n <= uint64(cfg.SampleFirst) {
+		return true
+	}
+	if cfg.SampleThereafter > 0 && (n-uint64(cfg.SampleFirst))%uint64(cfg.SampleThereafter) == 0 {
+		return true
+	}
+
+	counter.dropped.Add(1)
+	return false
+}
+
+// takeSampledReport 生成自上次报告以来被采样丢弃的统计行,每个调用点一行,appendNotice负责按日志格式格式化
+func (s *sampler) takeSampledReport(appendNotice func(b []byte, format string, args ...interface{}) []byte) []byte {
+	var b []byte
+	s.counters.Range(func(_, counterAny interface{}) bool {
+		counter := counterAny.(*sampleCounter)
+		dropped := counter.dropped.Swap(0)
+		if dropped == 0 {
+			return true
+		}
+		b = appendNotice(b, "sampled out %d lines at %s:%d", dropped, counter.fileName, counter.line)
+		return true
+	})
+	return b
+}
@@ -0,0 +1,50 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+func TestSampler(t *testing.T) {
+	cfg := &logger.FileLogConf{SampleIntervalSec: 60, SampleFirst: 3, SampleThereafter: 10}
+	var s sampler
+	var passed int
+	for i := 0; i < 103; i++ {
+		if s.check(1, cfg) {
+			passed++
+		}
+	}
+	if passed != 13 {
+		t.Fatalf("expect 13 lines passed, got %d", passed)
+	}
+	appendNotice := func(b []byte, format string, args ...interface{}) []byte {
+		return fmt.Appendf(b, format+"\n", args...)
+	}
+	report := string(s.takeSampledReport(appendNotice))
+	if !strings.Contains(report, "sampled out 90 lines at ") {
+		t.Fatalf("unexpected report: %s", report)
+	}
+	if len(s.takeSampledReport(appendNotice)) != 0 {
+		t.Fatal("report should be reset after taken")
+	}
+}
+
+func TestIsSampleEnabled(t *testing.T) {
+	for _, c := range []struct {
+		cfg     logger.FileLogConf
+		enabled bool
+	}{
+		{logger.FileLogConf{}, false},
+		{logger.FileLogConf{SampleIntervalSec: 60}, false},
+		{logger.FileLogConf{SampleIntervalSec: 60, SampleFirst: 3}, true},
+		{logger.FileLogConf{SampleIntervalSec: 60, SampleThereafter: 10}, true},
+		{logger.FileLogConf{SampleFirst: 3, SampleThereafter: 10}, false},
+	} {
+		if enabled := isSampleEnabled(&c.cfg); enabled != c.enabled {
+			t.Fatalf("expect enabled %v for %+v", c.enabled, c.cfg)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package writer
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+func TestSlogHandler(t *testing.T) {
+	fileWriter := newTestFileWriter(t, &FileWriterConf{BufChanLen: 10})
+
+	slogger := slog.New(logger.NewSlogHandler(fileWriter)).With("uid", 10).WithGroup("req")
+	slogger.Debug("ignored")
+	slogger.Warn("slow request", "cost", time.Second, slog.Group("peer", "ip", "10.0.0.1"))
+	if fileWriter.queue.len() != 1 {
+		t.Fatalf("expect 1 line, got %d", fileWriter.queue.len())
+	}
+	line := popLine(fileWriter)
+	if !strings.Contains(line, "WARN github.com/995933447/log-go/v2/loggo/logger/writer.TestSlogHandler:slog_handler_test.go") ||
+		!strings.HasSuffix(line, " slow request uid=10 req.cost=1s req.peer.ip=10.0.0.1\n") {
+		t.Fatalf("unexpected line: %s", line)
+	}
+}
+
+func TestSlogHandlerWithAlertWriter(t *testing.T) {
+	cfgLoader := newTestConfLoader(t, &logger.LogConf{
+		File:       logger.FileLogConf{Level: "ERR", LogInfoBeforeFileSizeBytes: -1},
+		AlertLevel: "INFO",
+		LevelOverride: logger.LevelOverrideConf{
+			Callers: map[string]string{"github.com/995933447/log-go/v2/loggo/logger/writer.TestSlogHandlerWithAlertWriter": "INFO"},
+		},
+	})
+	fileWriter := newTestFileWriter(t, &FileWriterConf{LogCfgLoader: cfgLoader, BufChanLen: 10})
+	var alerted []string
+	alertWriter := NewWithAlertWriter(fileWriter, cfgLoader, func(msg *logger.Msg) {
+		alerted = append(alerted, string(msg.Formatted))
+	})
+
+	// 经GetMsg/WriteMsg写入时按slog记录的调用方匹配级别覆盖
+	slogger := slog.New(logger.NewSlogHandler(alertWriter))
+	slogger.Info("enabled by caller override")
+	slogInfoNotMatched(slogger)
+	if fileWriter.queue.len() != 1 || len(alerted) != 2 {
+		t.Fatalf("expect 1 line and 2 alerts, got %d lines %d alerts", fileWriter.queue.len(), len(alerted))
+	}
+	if line := popLine(fileWriter); !strings.Contains(line, "TestSlogHandlerWithAlertWriter:slog_handler_test.go") ||
+		!strings.Contains(line, "enabled by caller override") {
+		t.Fatalf("unexpected line: %s", line)
+	}
+}
+
+func slogInfoNotMatched(slogger *slog.Logger) {
+	slogger.Info("not matched")
+}
@@ -0,0 +1,196 @@
+package writer
+
+import (
+	"errors"
+	"io"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+// TeeChild TeeWriter的子writer
+type TeeChild struct {
+	Writer   logger.Writer
+	MinLevel logger.Level // 子writer额外的最小级别,和子writer自身的IsLoggable同时生效
+	ReFormat bool         // 子writer使用自己的格式重新格式化(例如json),否则复用共享的格式化结果
+}
+
+func (c *TeeChild) isLoggable(level logger.Level) bool {
+	return level >= c.MinLevel && c.Writer.IsLoggable(level)
+}
+
+// NewTeeWriter 同一行日志同时写入多个writer,子writer的SkipCall需要比直接使用时多1
+func NewTeeWriter(children ...*TeeChild) *TeeWriter {
+	return &TeeWriter{
+		children: children,
+	}
+}
+
+var _ logger.Writer = (*TeeWriter)(nil)
+
+type TeeWriter struct {
+	children []*TeeChild
+}
+
+func (w *TeeWriter) GetChildren() []*TeeChild {
+	return w.children
+}
+
+func (w *TeeWriter) IsLoggable(level logger.Level) bool {
+	for _, child := range w.children {
+		if child.isLoggable(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *TeeWriter) DisableCacheCaller(disabled bool) {
+	for _, child := range w.children {
+		child.Writer.DisableCacheCaller(disabled)
+	}
+}
+
+func (w *TeeWriter) EnableStdoutPrinter() {
+	for _, child := range w.children {
+		child.Writer.EnableStdoutPrinter()
+	}
+}
+
+func (w *TeeWriter) DisableStdoutPrinter() {
+	for _, child := range w.children {
+		child.Writer.DisableStdoutPrinter()
+	}
+}
+
+func (w *TeeWriter) Write(level logger.Level, args ...interface{}) error {
+	var (
+		sharedMsg *logger.Msg
+		errs      []error
+	)
+	for _, child := range w.children {
+		if !child.isLoggable(level) {
+			continue
+		}
+
+		if child.ReFormat {
+			msg, err := child.Writer.GetMsg(level, args...)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err = child.Writer.WriteMsg(msg); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		if sharedMsg == nil {
+			var err error
+			if sharedMsg, err = child.Writer.GetMsg(level, args...); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := child.Writer.WriteMsg(sharedMsg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w *TeeWriter) WriteBySkipCall(level logger.Level, skipCall int, args ...interface{}) error {
+	var (
+		sharedMsg *logger.Msg
+		errs      []error
+	)
+	for _, child := range w.children {
+		if !child.isLoggable(level) {
+			continue
+		}
+
+		if child.ReFormat {
+			msg, err := child.Writer.GetMsgBySkipCall(level, skipCall, args...)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err = child.Writer.WriteMsg(msg); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		if sharedMsg == nil {
+			var err error
+			if sharedMsg, err = child.Writer.GetMsgBySkipCall(level, skipCall, args...); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := child.Writer.WriteMsg(sharedMsg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w *TeeWriter) WriteMsg(msg *logger.Msg) error {
+	var errs []error
+	for _, child := range w.children {
+		if !child.isLoggable(msg.Level) {
+			continue
+		}
+		if err := child.Writer.WriteMsg(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (w *TeeWriter) GetMsg(level logger.Level, args ...interface{}) (*logger.Msg, error) {
+	if len(w.children) == 0 {
+		return nil, errors.New("tee writer has no child")
+	}
+	return w.children[0].Writer.GetMsg(level, args...)
+}
+
+func (w *TeeWriter) GetMsgBySkipCall(level logger.Level, skipCall int, args ...interface{}) (*logger.Msg, error) {
+	if len(w.children) == 0 {
+		return nil, errors.New("tee writer has no child")
+	}
+	return w.children[0].Writer.GetMsgBySkipCall(level, skipCall, args...)
+}
+
+func (w *TeeWriter) GetSkipCall() int {
+	if len(w.children) == 0 {
+		return 0
+	}
+	return w.children[0].Writer.GetSkipCall() - 1
+}
+
+func (w *TeeWriter) Flush() error {
+	var errs []error
+	for _, child := range w.children {
+		if err := child.Writer.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭所有支持Close的子writer
+func (w *TeeWriter) Close() error {
+	var errs []error
+	for _, child := range w.children {
+		closer, ok := child.Writer.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
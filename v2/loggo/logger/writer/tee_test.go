@@ -0,0 +1,34 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+)
+
+func TestTeeWriter(t *testing.T) {
+	newChild := func(level string) *FileWriter {
+		return newTestFileWriter(t, &FileWriterConf{
+			SkipCall: 5,
+			LogCfgLoader: newTestConfLoader(t, &logger.LogConf{
+				File: logger.FileLogConf{Level: level, LogDebugBeforeFileSizeBytes: -1, LogInfoBeforeFileSizeBytes: -1},
+			}),
+			BufChanLen: 10,
+		})
+	}
+
+	debugWriter, errWriter := newChild("DBG"), newChild("ERR")
+	teeLogger := logger.NewLogger(NewTeeWriter(&TeeChild{Writer: debugWriter}, &TeeChild{Writer: errWriter}))
+	teeLogger.Infof("info %d", 1)
+	teeLogger.Errorf("err %d", 2)
+
+	if debugWriter.queue.len() != 2 || errWriter.queue.len() != 1 {
+		t.Fatalf("unexpected lines, debug:%d err:%d", debugWriter.queue.len(), errWriter.queue.len())
+	}
+	popLine(debugWriter)
+	debugLine, errLine := popLine(debugWriter), popLine(errWriter)
+	if debugLine != errLine || !strings.Contains(errLine, "TestTeeWriter:tee_test.go") {
+		t.Fatalf("unexpected lines:\n%s%s", debugLine, errLine)
+	}
+}
@@ -1,6 +1,8 @@
 package writer
 
 import (
+	"io"
+
 	"github.com/995933447/log-go/v2/loggo/logger"
 )
 
@@ -137,3 +139,11 @@ func (w *WithAlertWriter) WriteBySkipCall(level logger.Level, skipCall int, args
 func (w *WithAlertWriter) Flush() error {
 	return w.realWriter.Flush()
 }
+
+// Close 被包装的writer支持Close时关闭它
+func (w *WithAlertWriter) Close() error {
+	if closer, ok := w.realWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package loggo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+
+	"github.com/995933447/log-go/v2/loggo/logger"
+	"github.com/995933447/log-go/v2/loggo/logger/fmts"
+)
+
+var rePanicAfterRecover atomic.Bool
+
+// SetRePanicAfterRecover Recover记录panic之后是否继续向上panic,默认吞掉
+func SetRePanicAfterRecover(rePanic bool) {
+	rePanicAfterRecover.Store(rePanic)
+}
+
+// Recover 必须直接defer调用,记录panic的值和完整堆栈到异常日志
+func Recover() {
+	if r := recover(); r != nil {
+		logPanic(r, panicCallerPC())
+		if rePanicAfterRecover.Load() {
+			panic(r)
+		}
+	}
+}
+
+// GoSafe 启动协程执行fn,panic时由Recover处理
+func GoSafe(fn func()) {
+	go func() {
+		defer Recover()
+		fn()
+	}()
+}
+
+func logPanic(r interface{}, pc uintptr) {
+	panicLogger := exceptionLogger
+	if panicLogger == nil {
+		panicLogger = defaultLogger
+	}
+	if panicLogger == nil {
+		fmt.Printf("panic: %v\n%s", r, debug.Stack())
+		return
+	}
+
+	err := panicLogger.Write(logger.LevelPanic, "recovered panic: %v", r, &logger.Extra{
+		Fields: []logger.Field{logger.String(fmts.StackFieldKey, string(debug.Stack()))},
+		PC:     pc,
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+	if err = panicLogger.Flush(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// panicCallerPC 找到发生panic的函数,即runtime.gopanic之后第一个非runtime的调用
+func panicCallerPC() uintptr {
+	var rpc [32]uintptr
+	n := runtime.Callers(2, rpc[:])
+	var afterGopanic bool
+	for _, pc := range rpc[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if frame.Function == "runtime.gopanic" {
+			afterGopanic = true
+			continue
+		}
+		if afterGopanic && !strings.HasPrefix(frame.Function, "runtime.") {
+			return pc
+		}
+	}
+	return 0
+}
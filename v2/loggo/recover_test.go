@@ -0,0 +1,103 @@
+package loggo
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initTestExceptionLogger 把异常日志写到临时目录,返回读取日志文件内容的函数
+func initTestExceptionLogger(t *testing.T) func() string {
+	fileLogger, readLog := newTestFileLogger(t)
+	prevLogger := exceptionLogger
+	exceptionLogger = fileLogger
+	t.Cleanup(func() {
+		exceptionLogger = prevLogger
+	})
+	return readLog
+}
+
+func TestPanic(t *testing.T) {
+	readLog := initTestExceptionLogger(t)
+
+	expectPanic := func(expect string, fn func()) {
+		defer func() {
+			if r := recover(); r != expect {
+				t.Fatalf("expect panic %q, got %v", expect, r)
+			}
+			// panic之前已经flush,不需要等待Loop
+			if content := readLog(); !strings.Contains(content, "PANIC") || !strings.Contains(content, expect) {
+				t.Fatalf("%q should be flushed before panic, got %q", expect, content)
+			}
+		}()
+		fn()
+	}
+	expectPanic("panic by Panic", func() {
+		exceptionLogger.Panic("panic by Panic")
+	})
+	expectPanic("panic by Panicf 1", func() {
+		exceptionLogger.Panicf("panic by Panicf %d", 1)
+	})
+
+	// error原样panic,recover之后可以取出
+	for _, fn := range []func(err error){
+		func(err error) { exceptionLogger.Panic(err) },
+		func(err error) { exceptionLogger.Panicf("panic by Panicf: %w", err) },
+	} {
+		func() {
+			defer func() {
+				var pathErr *fs.PathError
+				if err, ok := recover().(error); !ok || !errors.As(err, &pathErr) {
+					t.Fatalf("expect panic with *fs.PathError, got %v", err)
+				}
+			}()
+			fn(&fs.PathError{Op: "open", Path: "app.txt", Err: fs.ErrNotExist})
+		}()
+	}
+}
+
+func panicInRecover(msg string) {
+	defer Recover()
+	panic(msg)
+}
+
+func TestRecover(t *testing.T) {
+	readLog := initTestExceptionLogger(t)
+
+	panicInRecover("swallowed")
+	content := readLog()
+	if !strings.Contains(content, "recovered panic: swallowed") || !strings.Contains(content, "panicInRecover:recover_test.go") ||
+		!strings.Contains(content, "runtime/debug.Stack") {
+		t.Fatalf("panic value, caller and stack should be logged, got %q", content)
+	}
+
+	SetRePanicAfterRecover(true)
+	defer SetRePanicAfterRecover(false)
+	func() {
+		defer func() {
+			if r := recover(); r != "re-panicked" {
+				t.Fatalf("expect re-panic, got %v", r)
+			}
+		}()
+		panicInRecover("re-panicked")
+	}()
+	if content = readLog(); !strings.Contains(content, "recovered panic: re-panicked") {
+		t.Fatalf("panic should be logged before re-panic, got %q", content)
+	}
+}
+
+func TestGoSafe(t *testing.T) {
+	readLog := initTestExceptionLogger(t)
+
+	GoSafe(func() {
+		panic("in goroutine")
+	})
+	for deadline := time.Now().Add(time.Second * 5); !strings.Contains(readLog(), "recovered panic: in goroutine"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("panic in goroutine should be recovered and logged, got %q", readLog())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}